@@ -72,20 +72,22 @@ func PrincipalFromCtx(ctx context.Context) (Principal, bool) {
 // It is important to note that the Authenticate middleware does not handle authentication failures
 // due to invalid or missing credentials. However, if the HttpAuthenticator returns a non-nil error
 // value, the Authenticate middleware will respond with an HTTP 500 InternalServerError.
+//
+// If the application was configured with WithAuthenticatorDecorator, the request is authenticated
+// with the HttpAuthenticator returned by the decorator.
 func Authenticate(authenticator HttpAuthenticator) func(next http.Handler) http.Handler {
 	if authenticator == nil {
 		panic("authenticator cannot be nil")
 	}
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-
 			tracer := otel.Tracer(internal.Scope)
 			ctx, span := tracer.Start(r.Context(), "Authenticate")
 			defer span.End()
 
 			r = r.WithContext(ctx)
 
-			principal, err := authenticator.Authenticate(r)
+			principal, err := settingsFromCtx(r.Context()).authenticator(authenticator).Authenticate(r)
 			if err != nil {
 				logger := log.LoggerFromCtx(r.Context())
 				logger.Error(fmt.Sprintf("Error authenticating request. %T.Authenticate returned an error", authenticator),
@@ -118,16 +120,7 @@ func Authenticate(authenticator HttpAuthenticator) func(next http.Handler) http.
 // with an HTTP 401 Unauthorized.
 func Authenticated() func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			principal, ok := PrincipalFromCtx(r.Context())
-			if !ok || principal == nil || principal.Anonymous() {
-				problem := Unauthorized()
-				_ = problem.Respond(w, r)
-				return
-			}
-
-			next.ServeHTTP(w, r)
-		})
+		return &authGuard{next: next}
 	}
 }
 
@@ -142,24 +135,32 @@ func Authenticated() func(next http.Handler) http.Handler {
 // Unauthorized.
 func RequireRole(role string) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		return &authGuard{role: role, next: next}
+	}
+}
 
-			principal, ok := PrincipalFromCtx(r.Context())
-			if !ok || principal == nil || principal.Anonymous() {
-				problem := Unauthorized()
-				_ = problem.Respond(w, r)
-				return
-			}
+// authGuard is the http.Handler returned by the Authenticated and RequireRole middleware. It is a
+// named type rather than a closure so the route inventory can report how a route is protected.
+type authGuard struct {
+	role string // Empty if only authentication is required
+	next http.Handler
+}
 
-			if !principal.HasRole(role) {
-				problem := Forbidden()
-				_ = problem.Respond(w, r)
-				return
-			}
+func (g *authGuard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	principal, ok := PrincipalFromCtx(r.Context())
+	if !ok || principal == nil || principal.Anonymous() {
+		problem := Unauthorized()
+		_ = problem.Respond(w, r)
+		return
+	}
 
-			next.ServeHTTP(w, r)
-		})
+	if g.role != "" && !principal.HasRole(g.role) {
+		problem := Forbidden()
+		_ = problem.Respond(w, r)
+		return
 	}
+
+	g.next.ServeHTTP(w, r)
 }
 
 type nopAuthenticator struct{}
@@ -0,0 +1,440 @@
+package yuna
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/form/v4"
+)
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+var decoder = form.NewDecoder()
+
+// maxFormMemory is the maximum number of bytes of a multipart form stored in memory when binding
+// form values, the remainder is stored on disk in temporary files.
+const maxFormMemory = 32 << 20
+
+// A BindError is returned by Request.Bind when values from the request cannot be converted to the
+// type of the fields they are bound to. Violations holds the reasons keyed by the name of the
+// parameter, so it can be sent to the client using BadRequest.
+type BindError struct {
+	Violations Violations
+}
+
+func (e *BindError) Error() string {
+	fields := make([]string, 0, len(e.Violations))
+	for field := range e.Violations {
+		fields = append(fields, field)
+	}
+	return fmt.Sprintf("bind: invalid values for %s", strings.Join(fields, ", "))
+}
+
+// binding sources in the order the struct tags are checked.
+var bindSources = []string{"path", "query", "header", "cookie", "form", "body"}
+
+// Bind populates the fields of dst, which must be a pointer to a struct, from the request based on
+// the struct tags of the fields:
+//
+//	type ListOrdersRequest struct {
+//		CustomerID string    `path:"customerId"`
+//		Limit      int       `query:"limit" default:"20"`
+//		Status     []string  `query:"status"`
+//		Tenant     string    `header:"X-Tenant"`
+//		Session    string    `cookie:"session"`
+//		Name       string    `form:"name"`
+//		Since      time.Time `query:"since"`
+//		Filter     Filter    `body:""`
+//	}
+//
+// The path, query, header, cookie, and form tags bind URL parameters, query parameters, headers,
+// cookies, and form values respectively, where the tag value is the name of the parameter. Form
+// values include the query parameters and, for POST, PUT, and PATCH requests, the URL-encoded or
+// multipart form in the body. A field tagged with body receives the request body decoded with
+// Decode, unless the body is a form.
+//
+// Before the tags are applied, the form values are decoded into dst using the go-playground/form
+// conventions, so fields without a tag are bound by their field name and nested keys such as
+// "address.city" and "items[0]" populate nested structs, slices, and maps. Values bound by a tag
+// take precedence over values decoded this way.
+//
+// The fields of embedded structs without a tag are bound as if they were declared on dst. A nil
+// embedded pointer is allocated if any of its fields are bound, unless its type is unexported.
+//
+// Values are converted to the type of the field, which may be a string, bool, integer, float,
+// time.Duration, a type implementing encoding.TextUnmarshaler such as time.Time or uuid.UUID, or a
+// pointer or slice of those. Slices receive every value of the parameter, other types only the
+// first. If a parameter is absent, the value of the default tag is used if present; the default of
+// a slice is a comma separated list.
+//
+// If any values cannot be converted, the remaining fields are still bound and a *BindError is
+// returned with the Violations keyed by parameter name. If the body cannot be decoded the error
+// returned by Decode is returned.
+func (r *Request) Bind(dst any) error {
+	return r.bind(dst, true)
+}
+
+// bind populates dst from the request. Unless decodeForm is set, only fields with a binding tag are
+// populated, which Typed handlers rely on so fields decoded from the body aren't overwritten by
+// query parameters with the same name.
+func (r *Request) bind(dst any, decodeForm bool) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("bind: destination must be a non-nil pointer")
+	}
+	rv = rv.Elem()
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("bind: destination must be a pointer to a struct, got %T", dst)
+	}
+
+	b := &binder{
+		req:   r,
+		query: r.raw.URL.Query(),
+	}
+	if decodeForm {
+		if err := b.decodeForm(dst); err != nil {
+			return err
+		}
+	}
+	if err := b.bindStruct(rv); err != nil {
+		return err
+	}
+	if len(b.violations) > 0 {
+		return &BindError{Violations: b.violations}
+	}
+	return nil
+}
+
+type binder struct {
+	req        *Request
+	query      map[string][]string
+	formParsed bool
+	violations Violations
+}
+
+func (b *binder) bindStruct(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := v.Field(i)
+
+		source, name, ok := bindSource(field)
+		if !ok {
+			// Embedded structs without a tag are treated as if their fields were declared on the
+			// outer struct.
+			if isEmbeddedStruct(field) {
+				if err := b.bindEmbedded(fv); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if source == "body" {
+			if err := b.bindBody(fv); err != nil {
+				return err
+			}
+			continue
+		}
+
+		vals, err := b.values(source, name)
+		if err != nil {
+			return err
+		}
+		if len(vals) == 0 {
+			def, ok := field.Tag.Lookup("default")
+			if !ok {
+				continue
+			}
+			vals = []string{def}
+			if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+				vals = strings.Split(def, ",")
+			}
+		}
+
+		// A value bound by a tag replaces any error from decoding the same parameter by name.
+		delete(b.violations, name)
+		if err := setValue(fv, vals); err != nil {
+			b.violations.Add(name, err.Error())
+		}
+	}
+	return nil
+}
+
+// bindEmbedded binds the fields of an embedded struct. A nil embedded pointer is only allocated if
+// any of its fields were bound, so it stays nil when the request has no values for it.
+func (b *binder) bindEmbedded(v reflect.Value) error {
+	if v.Kind() != reflect.Pointer {
+		return b.bindStruct(v)
+	}
+	if !v.IsNil() {
+		return b.bindStruct(v.Elem())
+	}
+	if !v.CanSet() {
+		// The pointer of an embedded unexported type cannot be allocated.
+		return nil
+	}
+	elem := reflect.New(v.Type().Elem())
+	if err := b.bindStruct(elem.Elem()); err != nil {
+		return err
+	}
+	if !elem.Elem().IsZero() {
+		v.Set(elem)
+	}
+	return nil
+}
+
+// isEmbeddedStruct reports whether the field is an embedded struct or pointer to a struct.
+func isEmbeddedStruct(field reflect.StructField) bool {
+	if !field.Anonymous {
+		return false
+	}
+	t := field.Type
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// bindSource returns the source and name of the parameter a field is bound to.
+func bindSource(field reflect.StructField) (source string, name string, ok bool) {
+	for _, source := range bindSources {
+		tag, ok := field.Tag.Lookup(source)
+		if !ok {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" || (name == "" && source != "body") {
+			continue
+		}
+		return source, name, true
+	}
+	return "", "", false
+}
+
+func (b *binder) values(source, name string) ([]string, error) {
+	raw := b.req.raw
+	switch source {
+	case "path":
+		if val := chi.URLParam(raw, name); val != "" {
+			return []string{val}, nil
+		}
+		return nil, nil
+	case "query":
+		return b.query[name], nil
+	case "header":
+		return raw.Header.Values(name), nil
+	case "cookie":
+		cookies := raw.CookiesNamed(name)
+		vals := make([]string, 0, len(cookies))
+		for _, cookie := range cookies {
+			vals = append(vals, cookie.Value)
+		}
+		return vals, nil
+	case "form":
+		if err := b.parseForm(); err != nil {
+			return nil, err
+		}
+		return raw.Form[name], nil
+	default:
+		return nil, nil
+	}
+}
+
+// decodeForm decodes the query parameters, and the form in the body if there is one, into dst by
+// field name, recording values which cannot be converted as violations.
+func (b *binder) decodeForm(dst any) error {
+	vals := b.query
+	if isForm(b.req.raw) {
+		if err := b.parseForm(); err != nil {
+			return err
+		}
+		vals = b.req.raw.Form
+	}
+	if len(vals) == 0 {
+		return nil
+	}
+
+	err := decoder.Decode(dst, vals)
+	var decodeErrs form.DecodeErrors
+	if errors.As(err, &decodeErrs) {
+		for name, err := range decodeErrs {
+			b.violations.Add(name, err.Error())
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("bind: %w", err)
+	}
+	return nil
+}
+
+// parseForm parses the form of the request the first time a form value is bound, so the body isn't
+// consumed unless the destination binds form values.
+func (b *binder) parseForm() error {
+	if b.formParsed {
+		return nil
+	}
+	b.formParsed = true
+
+	raw := b.req.raw
+	if mediaType, _, _ := mime.ParseMediaType(raw.Header.Get(HeaderContentType)); mediaType == MIMEMultipartForm {
+		if err := raw.ParseMultipartForm(maxFormMemory); err != nil {
+			return fmt.Errorf("bind: parse multipart form: %w", err)
+		}
+		return nil
+	}
+	if err := raw.ParseForm(); err != nil {
+		return fmt.Errorf("bind: parse form: %w", err)
+	}
+	return nil
+}
+
+func (b *binder) bindBody(v reflect.Value) error {
+	if !hasBody(b.req.raw) || isForm(b.req.raw) {
+		return nil
+	}
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		v.Set(reflect.New(v.Type().Elem()))
+	}
+	target := v.Addr().Interface()
+	if v.Kind() == reflect.Pointer {
+		target = v.Interface()
+	}
+	if err := b.req.Decode(target); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// isForm reports whether the request body is a URL-encoded or multipart form, which are bound using
+// the form tag rather than decoded.
+func isForm(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get(HeaderContentType))
+	return mediaType == MIMEApplicationForm || mediaType == MIMEMultipartForm
+}
+
+// hasBodyField reports whether the struct pointed to by dst has a field bound to the request body.
+func hasBodyField(dst any) bool {
+	t := reflect.TypeOf(dst)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		source, _, ok := bindSource(field)
+		if ok && source == "body" {
+			return true
+		}
+		if !ok && isEmbeddedStruct(field) && hasBodyField(reflect.New(field.Type).Interface()) {
+			return true
+		}
+	}
+	return false
+}
+
+// tagName returns the name in the struct tag with the given key, ignoring any options following a
+// comma. A name of "-" is treated as if the tag wasn't present.
+func tagName(field reflect.StructField, key string) string {
+	name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// setValue converts the string values and stores the result in v. Slices receive every value, all
+// other types only the first value.
+func setValue(v reflect.Value, vals []string) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValue(v.Elem(), vals)
+	}
+
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(vals[0])); err != nil {
+			return fmt.Errorf("invalid value %q", vals[0])
+		}
+		return nil
+	}
+
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(v.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setValue(slice.Index(i), []string{val}); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+
+	return setScalar(v, vals[0])
+}
+
+func setScalar(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Slice:
+		v.SetBytes([]byte(s))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid value %q: must be a boolean", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == durationType {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return fmt.Errorf("invalid value %q: must be a duration", s)
+			}
+			v.SetInt(int64(d))
+			return nil
+		}
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid value %q: must be an integer", s)
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid value %q: must be a non-negative integer", s)
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid value %q: must be a number", s)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package yuna
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type bindPaging struct {
+	Limit  int    `query:"limit" default:"20"`
+	Cursor string `query:"cursor"`
+}
+
+// BindTenant is exported because a nil embedded pointer to an unexported type cannot be allocated.
+type BindTenant struct {
+	Tenant string `header:"X-Tenant"`
+}
+
+type bindAddress struct {
+	City string
+	Zip  string
+}
+
+type bindRequest struct {
+	ID      int           `path:"id"`
+	Status  []string      `query:"status"`
+	Since   *time.Time    `query:"since"`
+	Timeout time.Duration `query:"timeout" default:"5s"`
+	Session string        `cookie:"session"`
+	Name    string        `form:"name"`
+	Ignored string        `query:"-"`
+	bindPaging
+	*BindTenant
+	Sort    string
+	Address bindAddress
+	Tags    []string
+}
+
+// bindReq returns a request for target with the given path parameters added to its route context.
+func bindReq(method, target string, body *strings.Reader, params map[string]string) *http.Request {
+	var r *http.Request
+	if body == nil {
+		r = httptest.NewRequest(method, target, nil)
+	} else {
+		r = httptest.NewRequest(method, target, body)
+	}
+	rctx := chi.NewRouteContext()
+	for k, v := range params {
+		rctx.URLParams.Add(k, v)
+	}
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestBind(t *testing.T) {
+	since := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		req    func() *http.Request
+		want   bindRequest
+		errors []string
+	}{
+		{
+			name: "path, query, header and cookie",
+			req: func() *http.Request {
+				r := bindReq(http.MethodGet, "/orders/42?status=open&status=shipped&since=2026-03-01T00:00:00Z&timeout=1m&limit=5&cursor=abc", nil, map[string]string{"id": "42"})
+				r.Header.Set("X-Tenant", "acme")
+				r.AddCookie(&http.Cookie{Name: "session", Value: "s1"})
+				return r
+			},
+			want: bindRequest{
+				ID:         42,
+				Status:     []string{"open", "shipped"},
+				Since:      &since,
+				Timeout:    time.Minute,
+				Session:    "s1",
+				bindPaging: bindPaging{Limit: 5, Cursor: "abc"},
+				BindTenant: &BindTenant{Tenant: "acme"},
+			},
+		},
+		{
+			name: "defaults",
+			req: func() *http.Request {
+				return bindReq(http.MethodGet, "/orders", nil, nil)
+			},
+			want: bindRequest{
+				Timeout:    5 * time.Second,
+				bindPaging: bindPaging{Limit: 20},
+			},
+		},
+		{
+			name: "untagged fields by name",
+			req: func() *http.Request {
+				return bindReq(http.MethodGet, "/orders?Sort=desc&Address.City=Berlin&Tags[0]=a&Tags[1]=b&Ignored=x", nil, nil)
+			},
+			want: bindRequest{
+				Timeout:    5 * time.Second,
+				bindPaging: bindPaging{Limit: 20},
+				Sort:       "desc",
+				Address:    bindAddress{City: "Berlin"},
+				Tags:       []string{"a", "b"},
+				Ignored:    "x",
+			},
+		},
+		{
+			name: "urlencoded form",
+			req: func() *http.Request {
+				r := bindReq(http.MethodPost, "/orders?limit=3", strings.NewReader("name=Ann&Address.Zip=10115"), nil)
+				r.Header.Set(HeaderContentType, MIMEApplicationForm)
+				return r
+			},
+			want: bindRequest{
+				Name:       "Ann",
+				Timeout:    5 * time.Second,
+				bindPaging: bindPaging{Limit: 3},
+				Address:    bindAddress{Zip: "10115"},
+			},
+		},
+		{
+			name: "invalid values",
+			req: func() *http.Request {
+				r := bindReq(http.MethodGet, "/orders/x?limit=ten&timeout=soon&since=yesterday", nil, map[string]string{"id": "x"})
+				r.Header.Set("X-Tenant", "acme")
+				return r
+			},
+			want: bindRequest{
+				BindTenant: &BindTenant{Tenant: "acme"},
+			},
+			errors: []string{"id", "limit", "since", "timeout"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bindRequest
+			err := newRequest(tt.req()).Bind(&got)
+
+			if len(tt.errors) > 0 {
+				var bindErr *BindError
+				if !errors.As(err, &bindErr) {
+					t.Fatalf("Bind() error = %v, want *BindError", err)
+				}
+				for _, name := range tt.errors {
+					if len(bindErr.Violations[name]) == 0 {
+						t.Errorf("no violation for %s in %v", name, bindErr.Violations)
+					}
+				}
+				if len(bindErr.Violations) != len(tt.errors) {
+					t.Errorf("violations = %v, want %v", bindErr.Violations, tt.errors)
+				}
+				if got.BindTenant == nil || got.Tenant != tt.want.Tenant {
+					t.Errorf("valid fields weren't bound alongside invalid ones: %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Bind() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Bind() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindMultipartForm(t *testing.T) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	_ = mw.WriteField("name", "Ann")
+	_ = mw.WriteField("Sort", "asc")
+	_ = mw.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/orders", &body)
+	r.Header.Set(HeaderContentType, mw.FormDataContentType())
+
+	var got bindRequest
+	if err := newRequest(r).Bind(&got); err != nil {
+		t.Fatalf("Bind() error = %v", err)
+	}
+	if got.Name != "Ann" || got.Sort != "asc" {
+		t.Errorf("Bind() = %+v", got)
+	}
+}
+
+func TestBindTagsOnly(t *testing.T) {
+	// Typed handlers only bind tagged fields, so untagged fields decoded from the body keep their
+	// values.
+	r := bindReq(http.MethodGet, "/orders?Sort=desc&limit=7", nil, nil)
+	got := bindRequest{Sort: "asc"}
+	if err := newRequest(r).bind(&got, false); err != nil {
+		t.Fatalf("bind() error = %v", err)
+	}
+	if got.Sort != "asc" || got.Limit != 7 {
+		t.Errorf("bind() = %+v", got)
+	}
+}
+
+func TestBindBody(t *testing.T) {
+	type CreateOrder struct {
+		Tenant string `header:"X-Tenant"`
+		Order  struct {
+			Item string `json:"item"`
+		} `body:""`
+	}
+	type embeddedBody struct {
+		*CreateOrder
+	}
+
+	if !hasBodyField(&CreateOrder{}) || !hasBodyField(&embeddedBody{}) {
+		t.Error("hasBodyField() = false for a struct with a body field")
+	}
+	if hasBodyField(&bindRequest{}) {
+		t.Error("hasBodyField() = true for a struct without a body field")
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"item":"hat"}`))
+	r.Header.Set(HeaderContentType, MIMEApplicationJSON)
+	r.Header.Set("X-Tenant", "acme")
+
+	var got embeddedBody
+	if err := newRequest(r).Bind(&got); err != nil {
+		t.Fatalf("Bind() error = %v", err)
+	}
+	if got.CreateOrder == nil || got.Tenant != "acme" || got.Order.Item != "hat" {
+		t.Errorf("Bind() = %+v", got.CreateOrder)
+	}
+}
+
+func TestBindDestination(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?"+url.Values{"limit": {"1"}}.Encode(), nil)
+	for _, dst := range []any{nil, bindRequest{}, (*bindRequest)(nil), new(string)} {
+		if err := newRequest(r).Bind(dst); err == nil {
+			t.Errorf("Bind(%T) error = nil", dst)
+		}
+	}
+
+	var ptr *bindPaging
+	if err := newRequest(r).Bind(&ptr); err != nil || ptr == nil || ptr.Limit != 1 {
+		t.Errorf("Bind(**T) = %+v, %v", ptr, err)
+	}
+}
@@ -0,0 +1,424 @@
+package yuna
+
+import (
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ErrUnsupportedMediaType is returned by Request.Decode when no Codec is registered for the
+// Content-Type of the request.
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// A Codec encodes and decodes values for one or more media types, such as application/json.
+//
+// Codecs are registered with WithCodecs and used by Request.Decode to decode request bodies, and by
+// ResponseBuilder and ProblemDetails to encode response bodies. Implementations of Codec must be
+// safe for concurrent use by multiple goroutines.
+type Codec interface {
+	// MediaTypes returns the media types handled by the Codec, without parameters, in order of
+	// preference.
+	MediaTypes() []string
+
+	// Encode writes the encoding of v to w.
+	Encode(w io.Writer, v any) error
+
+	// Decode reads the encoded value from r and stores it in the value pointed to by v.
+	Decode(r io.Reader, v any) error
+}
+
+// A NameTagger is a Codec that names fields using a struct tag, such as `json:"name"`. It is used
+// to name fields in Violations the same way the client named them. If a Codec doesn't implement
+// NameTagger the json struct tag is used.
+type NameTagger interface {
+	NameTag() string
+}
+
+// A SelectiveCodec is a Codec that can only encode some values, such as TextCodec. The media types
+// of a SelectiveCodec are only offered during content negotiation if it can encode the response
+// body.
+type SelectiveCodec interface {
+	Codec
+	CanEncode(v any) bool
+}
+
+// A DecodeError is returned by a Codec when the request body is malformed or doesn't match the
+// value it is decoded into. Request.DecodeAndValidate and Typed handlers use Field, Offset, and Line
+// to tell the client which part of the body is invalid. Codecs registered with WithCodecs can
+// return a *DecodeError to provide the same detail.
+type DecodeError struct {
+	// Field is the path of the offending field, such as "items.0.price", or empty if the error isn't
+	// specific to a field.
+	Field string
+
+	// Offset is the byte offset in the body where the error occurred, or zero if unknown.
+	Offset int64
+
+	// Line is the line in the body where the error occurred, or zero if unknown.
+	Line int
+
+	// Msg describes the problem, such as "expected number but got string".
+	Msg string
+
+	// Err is the underlying error returned by the decoder, if any.
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	switch {
+	case e.Field != "":
+		return fmt.Sprintf("decode: field %s: %s", e.Field, e.Msg)
+	case e.Offset > 0:
+		return fmt.Sprintf("decode: offset %d: %s", e.Offset, e.Msg)
+	case e.Line > 0:
+		return fmt.Sprintf("decode: line %d: %s", e.Line, e.Msg)
+	default:
+		return "decode: " + e.Msg
+	}
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// JSONCodec is a Codec for application/json using the encoding/json package.
+//
+// By default, fields in the body that don't match a field of the value are ignored, as is anything
+// following the top-level JSON value. Setting Strict rejects both, which catches misspelled field
+// names:
+//
+//	yuna.WithCodecs(yuna.JSONCodec{Strict: true})
+type JSONCodec struct {
+	// Strict rejects unknown fields and data following the top-level JSON value.
+	Strict bool
+}
+
+func (JSONCodec) MediaTypes() []string {
+	return []string{MIMEApplicationJSON}
+}
+
+func (JSONCodec) Encode(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func (c JSONCodec) Decode(r io.Reader, v any) error {
+	dec := json.NewDecoder(r)
+	if c.Strict {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		return jsonDecodeError(dec, err)
+	}
+	if c.Strict {
+		if _, err := dec.Token(); err != io.EOF {
+			return &DecodeError{
+				Offset: dec.InputOffset(),
+				Msg:    "unexpected data after top-level value",
+				Err:    err,
+			}
+		}
+	}
+	return nil
+}
+
+func (JSONCodec) NameTag() string {
+	return "json"
+}
+
+// jsonDecodeError converts errors returned by encoding/json into a *DecodeError. Errors reading the
+// body, including io.EOF for an empty body, are returned as is.
+func jsonDecodeError(dec *json.Decoder, err error) error {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &syntaxErr):
+		return &DecodeError{Offset: syntaxErr.Offset, Msg: syntaxErr.Error(), Err: err}
+	case errors.As(err, &typeErr):
+		msg := fmt.Sprintf("expected %s but got %s", typeErr.Type, typeErr.Value)
+		return &DecodeError{Field: typeErr.Field, Offset: typeErr.Offset, Msg: msg, Err: err}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &DecodeError{Offset: dec.InputOffset(), Msg: "unexpected end of JSON input", Err: err}
+	}
+
+	// encoding/json doesn't export an error type for unknown fields.
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		field = strings.Trim(field, `"`)
+		return &DecodeError{Field: field, Offset: dec.InputOffset(), Msg: "unknown field", Err: err}
+	}
+	return err
+}
+
+// defaultXMLMaxDepth is the maximum nesting depth of XML elements used when XMLCodec.MaxDepth is
+// zero.
+const defaultXMLMaxDepth = 100
+
+// XMLCodec is a Codec for application/xml and text/xml using the encoding/xml package.
+type XMLCodec struct {
+	// MaxDepth is the maximum nesting depth of elements in a request body. Deeper documents are
+	// rejected before they are decoded. If zero, a limit of 100 is used, a negative value disables
+	// the limit.
+	MaxDepth int
+}
+
+func (XMLCodec) MediaTypes() []string {
+	return []string{MIMEApplicationXML, MIMETextXML}
+}
+
+func (XMLCodec) Encode(w io.Writer, v any) error {
+	return xml.NewEncoder(w).Encode(v)
+}
+
+func (c XMLCodec) Decode(r io.Reader, v any) error {
+	maxDepth := c.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = defaultXMLMaxDepth
+	}
+
+	raw := xml.NewDecoder(r)
+	dec := raw
+	if maxDepth > 0 {
+		dec = xml.NewTokenDecoder(&depthLimiter{dec: raw, max: maxDepth})
+	}
+	if err := dec.Decode(v); err != nil {
+		var syntaxErr *xml.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return &DecodeError{Line: syntaxErr.Line, Offset: raw.InputOffset(), Msg: syntaxErr.Msg, Err: err}
+		}
+		return err
+	}
+	return nil
+}
+
+func (XMLCodec) NameTag() string {
+	return "xml"
+}
+
+// depthLimiter is an xml.TokenReader returning an error once elements are nested deeper than max.
+// Tokens are read with RawToken, the xml.Decoder reading from the depthLimiter verifies elements
+// are balanced and translates name spaces.
+type depthLimiter struct {
+	dec   *xml.Decoder
+	depth int
+	max   int
+}
+
+func (d *depthLimiter) Token() (xml.Token, error) {
+	tok, err := d.dec.RawToken()
+	if err != nil {
+		return tok, err
+	}
+	switch tok.(type) {
+	case xml.StartElement:
+		d.depth++
+		if d.depth > d.max {
+			line, _ := d.dec.InputPos()
+			return nil, &DecodeError{
+				Offset: d.dec.InputOffset(),
+				Line:   line,
+				Msg:    fmt.Sprintf("elements nested deeper than %d", d.max),
+			}
+		}
+	case xml.EndElement:
+		d.depth--
+	}
+	return tok, nil
+}
+
+// MsgpackCodec is a Codec for application/msgpack using the github.com/vmihailenco/msgpack package.
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) MediaTypes() []string {
+	return []string{MIMEApplicationMsgpack}
+}
+
+func (MsgpackCodec) Encode(w io.Writer, v any) error {
+	return msgpack.NewEncoder(w).Encode(v)
+}
+
+func (MsgpackCodec) Decode(r io.Reader, v any) error {
+	return msgpack.NewDecoder(r).Decode(v)
+}
+
+func (MsgpackCodec) NameTag() string {
+	return "msgpack"
+}
+
+// TextCodec is a Codec for text/plain. It encodes strings, byte slices, and values implementing
+// encoding.TextMarshaler, fmt.Stringer, or error, and decodes into a *string, *[]byte, or
+// encoding.TextUnmarshaler.
+type TextCodec struct{}
+
+var _ SelectiveCodec = TextCodec{}
+
+func (TextCodec) MediaTypes() []string {
+	return []string{MIMETextPlain}
+}
+
+func (TextCodec) CanEncode(v any) bool {
+	switch v.(type) {
+	case string, []byte, encoding.TextMarshaler, fmt.Stringer, error:
+		return true
+	default:
+		return false
+	}
+}
+
+func (TextCodec) Encode(w io.Writer, v any) error {
+	var err error
+	switch val := v.(type) {
+	case string:
+		_, err = io.WriteString(w, val)
+	case []byte:
+		_, err = w.Write(val)
+	case encoding.TextMarshaler:
+		var text []byte
+		if text, err = val.MarshalText(); err == nil {
+			_, err = w.Write(text)
+		}
+	case fmt.Stringer:
+		_, err = io.WriteString(w, val.String())
+	case error:
+		_, err = io.WriteString(w, val.Error())
+	default:
+		err = fmt.Errorf("text/plain cannot encode %T", v)
+	}
+	return err
+}
+
+func (TextCodec) Decode(r io.Reader, v any) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	switch val := v.(type) {
+	case *string:
+		*val = string(data)
+	case *[]byte:
+		*val = data
+	case encoding.TextUnmarshaler:
+		return val.UnmarshalText(data)
+	default:
+		return fmt.Errorf("text/plain cannot be decoded into %T", v)
+	}
+	return nil
+}
+
+// defaultCodecs returns the codecs registered by default, in order of preference.
+func defaultCodecs() []Codec {
+	return []Codec{JSONCodec{}, XMLCodec{}, MsgpackCodec{}, TextCodec{}}
+}
+
+// codecRegistry maps media types to the Codec handling them.
+type codecRegistry struct {
+	mediaTypes []string
+	codecs     map[string]Codec
+}
+
+// newCodecRegistry creates a registry with the codecs. If multiple codecs handle the same media
+// type, the last one wins but the media type keeps its original position in the order of
+// preference.
+func newCodecRegistry(codecs ...Codec) *codecRegistry {
+	cr := &codecRegistry{
+		mediaTypes: make([]string, 0),
+		codecs:     make(map[string]Codec),
+	}
+	for _, codec := range codecs {
+		for _, mediaType := range codec.MediaTypes() {
+			mediaType = strings.ToLower(mediaType)
+			if _, ok := cr.codecs[mediaType]; !ok {
+				cr.mediaTypes = append(cr.mediaTypes, mediaType)
+			}
+			cr.codecs[mediaType] = codec
+		}
+	}
+	return cr
+}
+
+// lookup returns the Codec for the media type. Media types using a structured syntax suffix, such
+// as application/problem+json, fall back to the Codec for the base format if no Codec is registered
+// for the media type itself.
+func (cr *codecRegistry) lookup(mediaType string) (Codec, bool) {
+	mediaType = strings.ToLower(mediaType)
+	if codec, ok := cr.codecs[mediaType]; ok {
+		return codec, true
+	}
+	if i := strings.LastIndexByte(mediaType, '+'); i >= 0 {
+		codec, ok := cr.codecs["application/"+mediaType[i+1:]]
+		return codec, ok
+	}
+	return nil, false
+}
+
+// offered returns the media types offered when negotiating the media type to encode the body as. If
+// produces isn't empty, only the media types declared with Produces are offered.
+func (cr *codecRegistry) offered(body any, produces []string) []string {
+	candidates := cr.mediaTypes
+	if len(produces) > 0 {
+		candidates = produces
+	}
+
+	offered := make([]string, 0, len(candidates))
+	for _, mediaType := range candidates {
+		codec, ok := cr.lookup(mediaType)
+		if !ok {
+			continue
+		}
+		if selective, ok := codec.(SelectiveCodec); ok && !selective.CanEncode(body) {
+			continue
+		}
+		offered = append(offered, mediaType)
+	}
+	return offered
+}
+
+// forContentType returns the Codec for a Content-Type header value. Requests without a Content-Type
+// are assumed to be JSON.
+func (cr *codecRegistry) forContentType(contentType string) (Codec, error) {
+	if strings.TrimSpace(contentType) == "" {
+		contentType = MIMEApplicationJSON
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedMediaType, contentType)
+	}
+	codec, ok := cr.lookup(mediaType)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedMediaType, mediaType)
+	}
+	return codec, nil
+}
+
+// nameTag returns the struct tag key used by the Codec for the Content-Type, which is used to name
+// the fields in Violations.
+func (cr *codecRegistry) nameTag(contentType string) string {
+	codec, err := cr.forContentType(contentType)
+	if err != nil {
+		return "json"
+	}
+	if tagger, ok := codec.(NameTagger); ok {
+		return tagger.NameTag()
+	}
+	return "json"
+}
+
+// contentType returns the value of the Content-Type header for a response encoded in the media type.
+// Textual media types are declared as UTF-8.
+func contentType(mediaType string) string {
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == MIMEApplicationJSON, mediaType == MIMEApplicationXML,
+		strings.HasSuffix(mediaType, "+json"), strings.HasSuffix(mediaType, "+xml"):
+		return mediaType + "; " + CharsetUTF8
+	default:
+		return mediaType
+	}
+}
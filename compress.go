@@ -0,0 +1,343 @@
+package yuna
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// A CompressOption configures the Compress middleware.
+type CompressOption func(c *compressConfig)
+
+type compressConfig struct {
+	level   int
+	minSize int
+	types   []string
+}
+
+// CompressLevel sets the compression level, from gzip.HuffmanOnly (-2) to gzip.BestCompression
+// (9). The default is gzip.DefaultCompression.
+func CompressLevel(level int) CompressOption {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		panic(fmt.Sprintf("invalid compression level %d", level))
+	}
+	return func(c *compressConfig) {
+		c.level = level
+	}
+}
+
+// CompressMinSize sets the minimum size of response bodies in bytes to compress, since compressing
+// small bodies costs more than it saves. The default is 1024 bytes.
+func CompressMinSize(n int) CompressOption {
+	return func(c *compressConfig) {
+		c.minSize = max(n, 0)
+	}
+}
+
+// CompressTypes sets the media types of the responses to compress, replacing the defaults. Media
+// types may use wildcards for the subtype, such as text/*. By default, text, JSON, XML, and
+// JavaScript are compressed, including media types with the +json and +xml suffixes.
+func CompressTypes(types ...string) CompressOption {
+	return func(c *compressConfig) {
+		c.types = normalizeMediaTypes(types)
+	}
+}
+
+// defaultCompressTypes are the media types compressed unless changed with CompressTypes.
+var defaultCompressTypes = []string{
+	"text/*",
+	MIMEApplicationJSON,
+	MIMEApplicationNDJSON,
+	MIMEApplicationXML,
+	MIMEApplicationJavascript,
+	"application/*+json",
+	"application/*+xml",
+	"image/svg+xml",
+}
+
+// compressEncodings are the content codings supported by Compress, in order of preference.
+var compressEncodings = []string{"gzip", "deflate"}
+
+// encoder is implemented by gzip.Writer and zlib.Writer.
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// Compress returns an HTTP middleware compressing response bodies with gzip or deflate, negotiated
+// with the Accept-Encoding header of the request.
+//
+// Only bodies of at least the minimum size with a compressible Content-Type are compressed, see
+// CompressMinSize and CompressTypes. Responses that are already encoded, partial content responses,
+// and responses with Cache-Control: no-transform are never compressed. When a response is
+// compressed, its Content-Length is removed, since it no longer applies, and a strong ETag becomes
+// weak, since the compressed representation isn't byte for byte identical.
+//
+// Responses flushed by the handler, such as Server-Sent Events and streams, are compressed
+// regardless of their size, and every flush sends what was compressed so far to the client.
+func Compress(opts ...CompressOption) HttpMiddleware {
+	conf := &compressConfig{
+		level:   gzip.DefaultCompression,
+		minSize: 1024,
+		types:   defaultCompressTypes,
+	}
+	for _, opt := range opts {
+		opt(conf)
+	}
+
+	pools := map[string]*sync.Pool{
+		"gzip": {New: func() any {
+			w, _ := gzip.NewWriterLevel(io.Discard, conf.level)
+			return w
+		}},
+		"deflate": {New: func() any {
+			w, _ := zlib.NewWriterLevel(io.Discard, conf.level)
+			return w
+		}},
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cw := &compressWriter{
+				ResponseWriter: w,
+				conf:           conf,
+				encoding:       negotiateEncoding(r.Header.Get(HeaderAcceptEncoding), compressEncodings),
+				status:         http.StatusOK,
+			}
+			if cw.encoding != "" {
+				cw.pool = pools[cw.encoding]
+			}
+			defer cw.finish()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// compressWriter buffers the beginning of the response body until it can decide whether to
+// compress it, after which the body is either compressed or passed through.
+type compressWriter struct {
+	http.ResponseWriter
+	conf     *compressConfig
+	encoding string
+	pool     *sync.Pool
+
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+
+	enc          encoder
+	counter      countingWriter
+	uncompressed uint64
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	// Informational responses are sent right away, they don't end the response.
+	if status >= 100 && status <= 199 && status != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		w.buf = append(w.buf, p...)
+		if len(w.buf) < w.conf.minSize {
+			return len(p), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	if w.enc != nil {
+		w.uncompressed += uint64(len(p))
+		return w.enc.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *compressWriter) Flush() {
+	_ = w.FlushError()
+}
+
+// FlushError sends the response compressed so far to the client. A response that is flushed is
+// treated as a stream, so it is compressed regardless of its size.
+func (w *compressWriter) FlushError() error {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		if err := w.decide(); err != nil {
+			return err
+		}
+	}
+	if w.enc != nil {
+		if err := w.enc.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide decides whether to compress the response, writes the header, and writes the buffered
+// body.
+func (w *compressWriter) decide() error {
+	w.decided = true
+	h := w.Header()
+
+	compressible := w.compressible()
+	if compressible {
+		addVary(h, HeaderAcceptEncoding)
+	}
+	if compressible && w.encoding != "" {
+		h.Set(HeaderContentEncoding, w.encoding)
+		h.Del(HeaderContentLength)
+		// Byte ranges of the compressed representation cannot be served.
+		h.Del(HeaderAcceptRanges)
+		if etag := h.Get(HeaderETag); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set(HeaderETag, "W/"+etag)
+		}
+
+		w.counter = countingWriter{w: w.ResponseWriter}
+		w.enc = w.pool.Get().(encoder)
+		w.enc.Reset(&w.counter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.enc != nil {
+		w.uncompressed += uint64(len(buf))
+		_, err := w.enc.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// compressible reports whether the response may be compressed, disregarding its size and whether
+// the client accepts compressed responses.
+func (w *compressWriter) compressible() bool {
+	h := w.Header()
+	switch {
+	case w.status < 200, w.status == http.StatusNoContent, w.status == http.StatusPartialContent,
+		w.status == http.StatusNotModified:
+		return false
+	case h.Get(HeaderContentEncoding) != "", h.Get(HeaderContentRange) != "":
+		return false
+	case headerHasToken(h, HeaderCacheControl, "no-transform"):
+		return false
+	}
+
+	// The Content-Type cannot be sniffed by the server from the compressed body.
+	ct := h.Get(HeaderContentType)
+	if ct == "" && len(w.buf) > 0 {
+		ct = http.DetectContentType(w.buf)
+		h.Set(HeaderContentType, ct)
+	}
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	return compressibleType(mediaType, w.conf.types)
+}
+
+// finish sends the rest of the response after the handler returned.
+func (w *compressWriter) finish() {
+	if !w.decided {
+		// Nothing was written, or the response was hijacked, so it is left to the server.
+		if !w.wroteHeader {
+			return
+		}
+		// The body is smaller than the minimum size, so it is sent uncompressed.
+		w.encoding = ""
+		_ = w.decide()
+		return
+	}
+	if w.enc == nil {
+		return
+	}
+
+	_ = w.enc.Close()
+	w.enc.Reset(io.Discard)
+	w.pool.Put(w.enc)
+	w.enc = nil
+
+	if rec := findCompressionRecorder(w.ResponseWriter); rec != nil {
+		rec.RecordCompression(w.uncompressed, w.counter.n)
+	}
+}
+
+// compressibleType reports whether the media type matches one of the types, which may use
+// wildcards for the subtype, or wildcards followed by a structured syntax suffix such as
+// application/*+json.
+func compressibleType(mediaType string, types []string) bool {
+	mediaType = strings.ToLower(mediaType)
+	for _, t := range types {
+		if prefix, suffix, ok := strings.Cut(t, "/*+"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") && strings.HasSuffix(mediaType, "+"+suffix) {
+				return true
+			}
+			continue
+		}
+		if matchesAny(mediaType, []string{t}) {
+			return true
+		}
+	}
+	return false
+}
+
+// compressionRecorder is implemented by the ResponseWriter recording the response metrics, to
+// report both the compressed and uncompressed size of response bodies.
+type compressionRecorder interface {
+	RecordCompression(uncompressed, compressed uint64)
+}
+
+// findCompressionRecorder unwraps the ResponseWriter until it finds a compressionRecorder.
+func findCompressionRecorder(w http.ResponseWriter) compressionRecorder {
+	for {
+		if rec, ok := w.(compressionRecorder); ok {
+			return rec
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = u.Unwrap()
+	}
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n uint64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += uint64(n)
+	return n, err
+}
@@ -0,0 +1,258 @@
+package yuna
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxETagBufferSize is the size of the largest response the ETag middleware buffers to compute its
+// entity tag.
+const maxETagBufferSize = 1 << 20
+
+// ETag returns an HTTP middleware computing a strong entity tag for successful responses to GET and
+// HEAD requests by hashing the response body, and answering requests whose If-None-Match header
+// matches it with 304 Not Modified, or whose If-Match header doesn't match it with 412 Precondition
+// Failed. This saves bandwidth for clients revalidating cached responses,
+// although the handler still runs.
+//
+// To compute the entity tag the response is buffered. Responses that already have an ETag header,
+// that aren't 200 OK, that are flushed by the handler, such as streams, or that are larger than
+// 1 MiB are sent as is.
+func ETag() HttpMiddleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ew := &etagWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(ew, r)
+			ew.finish(r)
+		})
+	}
+}
+
+// etagWriter buffers the response to compute its entity tag, unless it switched to passing the
+// response through.
+type etagWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	passthrough bool
+	buf         bytes.Buffer
+}
+
+func (w *etagWriter) WriteHeader(status int) {
+	// Informational responses are sent right away, they don't end the response.
+	if status >= 100 && status <= 199 && status != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	if status != http.StatusOK || w.Header().Get(HeaderETag) != "" {
+		w.startPassthrough()
+	}
+}
+
+func (w *etagWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.passthrough && w.buf.Len()+len(p) > maxETagBufferSize {
+		w.startPassthrough()
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(p)
+	}
+	return w.buf.Write(p)
+}
+
+func (w *etagWriter) Flush() {
+	_ = w.FlushError()
+}
+
+// FlushError sends the buffered response, and the rest of the response as it is written, since a
+// handler flushing the response expects it to reach the client.
+func (w *etagWriter) FlushError() error {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.passthrough {
+		w.startPassthrough()
+	}
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (w *etagWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *etagWriter) startPassthrough() {
+	w.passthrough = true
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.buf.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
+
+// finish sends the buffered response with its entity tag, 304 Not Modified if the client already
+// has it, or 412 Precondition Failed if the If-Match header of the request doesn't match it.
+func (w *etagWriter) finish(r *http.Request) {
+	if w.passthrough {
+		return
+	}
+
+	sum := sha256.Sum256(w.buf.Bytes())
+	etag := hashETag(sum[:])
+	w.Header().Set(HeaderETag, etag)
+	switch checkPreconditions(r, etag, time.Time{}) {
+	case http.StatusNotModified:
+		writeNotModified(w.ResponseWriter)
+		return
+	case http.StatusPreconditionFailed:
+		w.Header().Del(HeaderETag)
+		_ = PreconditionFailed().Respond(w.ResponseWriter, r)
+		return
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	_, _ = w.ResponseWriter.Write(w.buf.Bytes())
+}
+
+// IfMatch checks the If-Match header of a request to modify a resource against the current entity
+// tag of the resource, implementing optimistic concurrency control: a client can only modify the
+// resource if it wasn't modified since the client retrieved it.
+//
+// IfMatch returns nil if the header contains the current entity tag or "*", a 428 Precondition
+// Required problem if the header is missing, and a 412 Precondition Failed problem otherwise. An
+// empty currentETag means the resource doesn't exist, which never matches. Entity tags are compared
+// using the strong comparison of RFC 9110, so weak entity tags never match.
+//
+//	order, err := repo.Get(ctx, id)
+//	if err != nil {
+//		return yuna.InternalServerError(err)
+//	}
+//	if problem := r.IfMatch(order.Version); problem != nil {
+//		return problem
+//	}
+func (r *Request) IfMatch(currentETag string) *ProblemDetails {
+	header := strings.Join(r.raw.Header.Values(HeaderIfMatch), ",")
+	if strings.TrimSpace(header) == "" {
+		return PreconditionRequired()
+	}
+	if currentETag != "" && etagMatches(header, quoteETag(currentETag), false) {
+		return nil
+	}
+	return PreconditionFailed()
+}
+
+// checkPreconditions evaluates the conditional headers of a request against the entity tag and
+// modification time of the current representation, which are ignored if empty, in the order defined
+// by RFC 9110 section 13.2.2. It returns 412 Precondition Failed or 304 Not Modified if the request
+// must be answered with that status instead, or 0 if the request should be processed normally.
+//
+// If-Unmodified-Since is only evaluated without If-Match, and If-Modified-Since only without
+// If-None-Match. If-Match uses the strong comparison and If-None-Match the weak comparison. A
+// matching If-None-Match results in 304 for GET and HEAD requests and 412 for any other method.
+func checkPreconditions(r *http.Request, etag string, modtime time.Time) int {
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+
+	if header := strings.Join(r.Header.Values(HeaderIfMatch), ","); header != "" {
+		if !etagMatches(header, etag, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if header := r.Header.Get(HeaderIfUnmodifiedSince); header != "" && !modtime.IsZero() {
+		since, err := http.ParseTime(header)
+		if err == nil && modtime.Truncate(time.Second).After(since) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	if header := strings.Join(r.Header.Values(HeaderIfNoneMatch), ","); header != "" {
+		if etagMatches(header, etag, true) {
+			if safe {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if header := r.Header.Get(HeaderIfModifiedSince); header != "" && safe && !modtime.IsZero() {
+		since, err := http.ParseTime(header)
+		if err == nil && !modtime.Truncate(time.Second).After(since) {
+			return http.StatusNotModified
+		}
+	}
+	return 0
+}
+
+// writeNotModified sends 304 Not Modified, removing the headers describing the content that isn't
+// sent.
+func writeNotModified(w http.ResponseWriter) {
+	h := w.Header()
+	h.Del(HeaderContentType)
+	h.Del(HeaderContentLength)
+	h.Del(HeaderContentEncoding)
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// etagMatches reports whether the list of entity tags of an If-Match or If-None-Match header
+// contains the entity tag, using the weak or strong comparison defined by RFC 9110. The list "*"
+// matches any entity tag.
+func etagMatches(list, etag string, weak bool) bool {
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+	etagOpaque, etagWeak := strings.CutPrefix(etag, "W/")
+	if etagWeak && !weak {
+		return false
+	}
+
+	for list != "" {
+		list = strings.TrimLeft(list, " \t,")
+		candidate, candidateWeak := strings.CutPrefix(list, "W/")
+		if !strings.HasPrefix(candidate, `"`) {
+			// Skip the invalid element.
+			_, list, _ = strings.Cut(list, ",")
+			continue
+		}
+		end := strings.IndexByte(candidate[1:], '"')
+		if end < 0 {
+			return false
+		}
+		opaque := candidate[:end+2]
+		list = candidate[end+2:]
+
+		if candidateWeak && !weak {
+			continue
+		}
+		if opaque == etagOpaque {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteETag quotes the entity tag unless it is already quoted.
+func quoteETag(tag string) string {
+	if strings.HasPrefix(tag, `"`) || strings.HasPrefix(tag, `W/"`) {
+		return tag
+	}
+	if rest, ok := strings.CutPrefix(tag, "W/"); ok {
+		return `W/"` + rest + `"`
+	}
+	return `"` + tag + `"`
+}
+
+// hashETag formats a strong entity tag from a hash of the content.
+func hashETag(sum []byte) string {
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
@@ -0,0 +1,317 @@
+package yuna
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckPreconditions(t *testing.T) {
+	modtime := time.Date(2026, 3, 1, 12, 0, 0, 500, time.UTC)
+	before := modtime.Add(-time.Hour).Format(http.TimeFormat)
+	at := modtime.Format(http.TimeFormat)
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		etag    string
+		modtime time.Time
+		want    int
+	}{
+		{
+			name: "no conditions",
+			etag: `"v1"`,
+			want: 0,
+		},
+		{
+			name:    "if-none-match matches",
+			headers: map[string]string{HeaderIfNoneMatch: `"v0", "v1"`},
+			etag:    `"v1"`,
+			want:    http.StatusNotModified,
+		},
+		{
+			name:    "if-none-match doesn't match",
+			headers: map[string]string{HeaderIfNoneMatch: `"v0"`},
+			etag:    `"v1"`,
+			want:    0,
+		},
+		{
+			name:    "if-none-match uses weak comparison",
+			headers: map[string]string{HeaderIfNoneMatch: `W/"v1"`},
+			etag:    `"v1"`,
+			want:    http.StatusNotModified,
+		},
+		{
+			name:    "if-none-match star",
+			headers: map[string]string{HeaderIfNoneMatch: "*"},
+			etag:    `"v1"`,
+			want:    http.StatusNotModified,
+		},
+		{
+			name:    "if-none-match on head",
+			method:  http.MethodHead,
+			headers: map[string]string{HeaderIfNoneMatch: `"v1"`},
+			etag:    `"v1"`,
+			want:    http.StatusNotModified,
+		},
+		{
+			name:    "if-none-match on unsafe method fails",
+			method:  http.MethodPut,
+			headers: map[string]string{HeaderIfNoneMatch: "*"},
+			etag:    `"v1"`,
+			want:    http.StatusPreconditionFailed,
+		},
+		{
+			name:    "if-modified-since not modified",
+			headers: map[string]string{HeaderIfModifiedSince: at},
+			modtime: modtime,
+			want:    http.StatusNotModified,
+		},
+		{
+			name:    "if-modified-since modified",
+			headers: map[string]string{HeaderIfModifiedSince: before},
+			modtime: modtime,
+			want:    0,
+		},
+		{
+			name:    "if-modified-since ignored for unsafe methods",
+			method:  http.MethodPost,
+			headers: map[string]string{HeaderIfModifiedSince: at},
+			modtime: modtime,
+			want:    0,
+		},
+		{
+			name:    "if-modified-since invalid date",
+			headers: map[string]string{HeaderIfModifiedSince: "yesterday"},
+			modtime: modtime,
+			want:    0,
+		},
+		{
+			name:    "if-modified-since without modification time",
+			headers: map[string]string{HeaderIfModifiedSince: at},
+			want:    0,
+		},
+		{
+			name:    "if-none-match takes precedence over if-modified-since",
+			headers: map[string]string{HeaderIfNoneMatch: `"v0"`, HeaderIfModifiedSince: at},
+			etag:    `"v1"`,
+			modtime: modtime,
+			want:    0,
+		},
+		{
+			name:    "if-match matches",
+			method:  http.MethodPut,
+			headers: map[string]string{HeaderIfMatch: `"v0", "v1"`},
+			etag:    `"v1"`,
+			want:    0,
+		},
+		{
+			name:    "if-match doesn't match",
+			method:  http.MethodPut,
+			headers: map[string]string{HeaderIfMatch: `"v0"`},
+			etag:    `"v1"`,
+			want:    http.StatusPreconditionFailed,
+		},
+		{
+			name:    "if-match uses strong comparison",
+			headers: map[string]string{HeaderIfMatch: `W/"v1"`},
+			etag:    `W/"v1"`,
+			want:    http.StatusPreconditionFailed,
+		},
+		{
+			name:    "if-match star",
+			headers: map[string]string{HeaderIfMatch: "*"},
+			etag:    `"v1"`,
+			want:    0,
+		},
+		{
+			name:    "if-match without entity tag",
+			headers: map[string]string{HeaderIfMatch: `"v1"`},
+			want:    http.StatusPreconditionFailed,
+		},
+		{
+			name:    "if-unmodified-since unmodified",
+			method:  http.MethodDelete,
+			headers: map[string]string{HeaderIfUnmodifiedSince: at},
+			modtime: modtime,
+			want:    0,
+		},
+		{
+			name:    "if-unmodified-since modified",
+			method:  http.MethodDelete,
+			headers: map[string]string{HeaderIfUnmodifiedSince: before},
+			modtime: modtime,
+			want:    http.StatusPreconditionFailed,
+		},
+		{
+			name:    "if-match takes precedence over if-unmodified-since",
+			headers: map[string]string{HeaderIfMatch: `"v1"`, HeaderIfUnmodifiedSince: before},
+			etag:    `"v1"`,
+			modtime: modtime,
+			want:    0,
+		},
+		{
+			name:    "failed if-match takes precedence over if-none-match",
+			headers: map[string]string{HeaderIfMatch: `"v0"`, HeaderIfNoneMatch: `"v1"`},
+			etag:    `"v1"`,
+			want:    http.StatusPreconditionFailed,
+		},
+		{
+			name:    "failed if-unmodified-since takes precedence over if-modified-since",
+			headers: map[string]string{HeaderIfUnmodifiedSince: before, HeaderIfModifiedSince: at},
+			modtime: modtime,
+			want:    http.StatusPreconditionFailed,
+		},
+		{
+			name:    "passed if-match falls through to if-none-match",
+			headers: map[string]string{HeaderIfMatch: "*", HeaderIfNoneMatch: `"v1"`},
+			etag:    `"v1"`,
+			want:    http.StatusNotModified,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			r := httptest.NewRequest(method, "/orders/1", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if got := checkPreconditions(r, tt.etag, tt.modtime); got != tt.want {
+				t.Errorf("checkPreconditions() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		list       string
+		etag       string
+		weakWant   bool
+		strongWant bool
+	}{
+		{list: `"a"`, etag: `"a"`, weakWant: true, strongWant: true},
+		{list: `W/"a"`, etag: `W/"a"`, weakWant: true, strongWant: false},
+		{list: `W/"a"`, etag: `"a"`, weakWant: true, strongWant: false},
+		{list: `"a"`, etag: `W/"a"`, weakWant: true, strongWant: false},
+		{list: `"a"`, etag: `"b"`, weakWant: false, strongWant: false},
+		{list: ` "x" ,W/"y", "a"`, etag: `"a"`, weakWant: true, strongWant: true},
+		{list: `"a,b"`, etag: `"a,b"`, weakWant: true, strongWant: true},
+		{list: `a, "a"`, etag: `"a"`, weakWant: true, strongWant: true},
+		{list: `"a`, etag: `"a"`, weakWant: false, strongWant: false},
+		{list: `*`, etag: `"a"`, weakWant: true, strongWant: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.list+" "+tt.etag, func(t *testing.T) {
+			if got := etagMatches(tt.list, tt.etag, true); got != tt.weakWant {
+				t.Errorf("weak comparison = %t, want %t", got, tt.weakWant)
+			}
+			if got := etagMatches(tt.list, tt.etag, false); got != tt.strongWant {
+				t.Errorf("strong comparison = %t, want %t", got, tt.strongWant)
+			}
+		})
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		current string
+		want    int
+	}{
+		{name: "missing header", current: "v1", want: http.StatusPreconditionRequired},
+		{name: "matches", header: `"v1"`, current: "v1", want: 0},
+		{name: "matches quoted", header: `"v1"`, current: `"v1"`, want: 0},
+		{name: "doesn't match", header: `"v0"`, current: "v1", want: http.StatusPreconditionFailed},
+		{name: "weak never matches", header: `W/"v1"`, current: "W/v1", want: http.StatusPreconditionFailed},
+		{name: "star", header: "*", current: "v1", want: 0},
+		{name: "star without resource", header: "*", current: "", want: http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/orders/1", nil)
+			if tt.header != "" {
+				r.Header.Set(HeaderIfMatch, tt.header)
+			}
+			problem := newRequest(r).IfMatch(tt.current)
+			got := 0
+			if problem != nil {
+				got = problem.StatusCode
+			}
+			if got != tt.want {
+				t.Errorf("IfMatch() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConditionalResponses(t *testing.T) {
+	modtime := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	builder := wrapFn(func(r *Request) Responder {
+		return Ok(map[string]string{"id": "1"}).ETag("v1", false).LastModified(modtime)
+	})
+	etagged := ETag()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderContentType, MIMEApplicationJSON)
+		_, _ = w.Write([]byte(`{"id":"1"}`))
+	}))
+
+	tests := []struct {
+		name       string
+		handler    http.Handler
+		method     string
+		headers    map[string]string
+		wantStatus int
+	}{
+		{name: "builder unconditional", handler: builder, wantStatus: http.StatusOK},
+		{name: "builder not modified", handler: builder, headers: map[string]string{HeaderIfNoneMatch: `"v1"`}, wantStatus: http.StatusNotModified},
+		{name: "builder not modified since", handler: builder, headers: map[string]string{HeaderIfModifiedSince: modtime.Format(http.TimeFormat)}, wantStatus: http.StatusNotModified},
+		{name: "builder precondition failed", handler: builder, headers: map[string]string{HeaderIfMatch: `"v0"`}, wantStatus: http.StatusPreconditionFailed},
+		// The handler of an unsafe method already ran, so its preconditions must be checked up
+		// front with IfMatch rather than when responding.
+		{name: "builder ignores unsafe methods", handler: builder, method: http.MethodPut, headers: map[string]string{HeaderIfMatch: `"v0"`}, wantStatus: http.StatusOK},
+		{name: "middleware unconditional", handler: etagged, wantStatus: http.StatusOK},
+		{name: "middleware not modified", handler: etagged, headers: map[string]string{HeaderIfNoneMatch: "*"}, wantStatus: http.StatusNotModified},
+		{name: "middleware precondition failed", handler: etagged, headers: map[string]string{HeaderIfMatch: `"v0"`}, wantStatus: http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			r := httptest.NewRequest(method, "/orders/1", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rec, r)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			switch rec.Code {
+			case http.StatusNotModified:
+				if rec.Body.Len() != 0 || rec.Header().Get(HeaderContentType) != "" {
+					t.Errorf("304 has content: %q %q", rec.Header().Get(HeaderContentType), rec.Body.String())
+				}
+				if rec.Header().Get(HeaderETag) == "" {
+					t.Error("304 has no ETag")
+				}
+			case http.StatusOK:
+				if rec.Header().Get(HeaderETag) == "" {
+					t.Error("200 has no ETag")
+				}
+			}
+		})
+	}
+}
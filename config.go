@@ -0,0 +1,347 @@
+package yuna
+
+import (
+	"crypto/tls"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redactedValue replaces sensitive values in the configuration exposed by the /config endpoint.
+const redactedValue = "[REDACTED]"
+
+// Secret is a string that is redacted when exposed by the /config endpoint on the operations server,
+// or otherwise encoded as JSON. It is intended for configuration values such as passwords, tokens,
+// and API keys.
+type Secret string
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(redactedValue)
+}
+
+// String returns the redacted value so the secret isn't accidentally logged.
+func (s Secret) String() string {
+	return redactedValue
+}
+
+// Value returns the underlying secret value.
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// sensitiveKeys are substrings of map keys and field names whose values are redacted even if they
+// weren't explicitly marked as sensitive.
+var sensitiveKeys = []string{"password", "passwd", "secret", "token", "apikey", "api_key", "credential", "privatekey", "private_key"}
+
+// RegisterConfig contributes a section to the /config endpoint on the operations server, alongside
+// the effective configuration of Yuna.
+//
+// The value is read every time the endpoint is requested, so a pointer can be provided to reflect the
+// current configuration. Values are encoded using their json struct tags. Struct fields tagged with
+// `sensitive:"true"`, values of type Secret, and fields or map keys that look like they hold
+// credentials, such as "password" or "token", are redacted.
+func (z *Yuna) RegisterConfig(section string, cfg any) {
+	z.configSections.Lock()
+	defer z.configSections.Unlock()
+
+	if section == "yuna" {
+		z.logger.Warn("Config section yuna is reserved. This operation will have no impact.")
+		return
+	}
+	z.configSections.sections[section] = cfg
+}
+
+type configSections struct {
+	sync.Mutex
+	sections map[string]any
+}
+
+// effectiveConfig returns a representation of the configuration Yuna is running with.
+func (z *Yuna) effectiveConfig() map[string]any {
+	conf := z.config
+
+	authenticator := ""
+	if conf.authenticator != nil {
+		authenticator = fmt.Sprintf("%T", conf.authenticator)
+	}
+
+	signals := make([]string, 0, len(conf.shutdownSignals))
+	for _, sig := range conf.shutdownSignals {
+		signals = append(signals, sig.String())
+	}
+
+	server := map[string]any{
+		"httpPort":                conf.httpPort,
+		"listenAddress":           conf.listenAddress,
+		"unixSocket":              conf.unixSocketPath,
+		"systemdSocketActivation": conf.systemdSocketActivation,
+		"readTimeout":             conf.readTimeout.String(),
+		"readHeaderTimeout":       conf.readHeaderTimeout.String(),
+		"writeTimeout":            conf.writeTimeout.String(),
+		"idleTimeout":             conf.idleTimeout.String(),
+		"h2c":                     conf.h2c,
+		"tls":                     tlsSettingsInfo(conf.tls, z.appCerts),
+		"clientAuth":              conf.clientAuth.String(),
+		"clientCAs":               conf.clientCAs != nil,
+	}
+	if conf.http2 != nil {
+		server["http2"] = map[string]any{
+			"maxConcurrentStreams": conf.http2.MaxConcurrentStreams,
+			"maxReadFrameSize":     conf.http2.MaxReadFrameSize,
+		}
+	}
+
+	return map[string]any{
+		"server": server,
+		"lifecycle": map[string]any{
+			"shutdownSignals": signals,
+			"shutdownTimeout": conf.shutdownTimeout.String(),
+			"preStopDelay":    conf.preStopDelay.String(),
+			"hookTimeout":     conf.hookTimeout.String(),
+		},
+		"operations": map[string]any{
+			"httpPort":            conf.operationHTTPPort,
+			"listenAddress":       conf.operationListenAddr,
+			"metricsEnabled":      conf.metricsEnabled,
+			"pprofEnabled":        conf.pprofEnabled,
+			"healthcheckEnabled":  conf.healthcheckEnabled,
+			"healthcheckBasePath": conf.healthcheckBasePath,
+			"tls":                 tlsSettingsInfo(conf.operationTLS, z.opsCerts),
+		},
+		"telemetry": map[string]any{
+			"traceProvider":          fmt.Sprintf("%T", conf.traceProvider),
+			"meterProvider":          fmt.Sprintf("%T", conf.meterProvider),
+			"requestDurationBuckets": conf.requestDurationBuckets,
+		},
+		"logging": map[string]any{
+			"level": z.logger.Level().String(),
+		},
+		"authentication": map[string]any{
+			"enabled":       conf.authenticator != nil,
+			"authenticator": authenticator,
+		},
+		"validation": map[string]any{
+			"validator": fmt.Sprintf("%T", conf.validator),
+		},
+		"codecs": codecsInfo(conf.codecs),
+		"requests": map[string]any{
+			"maxBodySize":   conf.maxRequestBodySize,
+			"redirectHosts": conf.redirectHosts,
+		},
+		"cors": corsInfo(conf.cors),
+	}
+}
+
+func corsInfo(policy *CORSPolicy) map[string]any {
+	if policy == nil {
+		return map[string]any{"enabled": false}
+	}
+	return map[string]any{
+		"enabled":               true,
+		"allowedOrigins":        policy.AllowedOrigins,
+		"allowedOriginPatterns": policy.AllowedOriginPatterns,
+		"allowedMethods":        policy.AllowedMethods,
+		"allowedHeaders":        policy.AllowedHeaders,
+		"exposedHeaders":        policy.ExposedHeaders,
+		"allowCredentials":      policy.AllowCredentials,
+		"maxAge":                policy.MaxAge.String(),
+	}
+}
+
+func codecsInfo(custom []Codec) map[string]string {
+	registry := newCodecRegistry(append(defaultCodecs(), custom...)...)
+	info := make(map[string]string, len(registry.mediaTypes))
+	for _, mediaType := range registry.mediaTypes {
+		info[mediaType] = fmt.Sprintf("%T", registry.codecs[mediaType])
+	}
+	return info
+}
+
+func tlsSettingsInfo(settings *tlsSettings, loader *certLoader) map[string]any {
+	if settings == nil {
+		return map[string]any{"enabled": false}
+	}
+
+	suites := make([]string, 0, len(settings.cipherSuites))
+	for _, id := range settings.cipherSuites {
+		suites = append(suites, tls.CipherSuiteName(id))
+	}
+
+	info := map[string]any{
+		"enabled":        true,
+		"certFile":       settings.certFile,
+		"keyFile":        settings.keyFile,
+		"minVersion":     tls.VersionName(settings.minVersion),
+		"cipherSuites":   suites,
+		"nextProtos":     settings.nextProtos,
+		"reloadInterval": settings.reloadInterval.String(),
+	}
+	if notAfter := loader.NotAfter(); !notAfter.IsZero() {
+		info["notAfter"] = notAfter
+	}
+	return info
+}
+
+func (z *Yuna) configHandler(w http.ResponseWriter, r *http.Request) {
+	resp := map[string]any{
+		"yuna": z.effectiveConfig(),
+	}
+
+	z.configSections.Lock()
+	for name, section := range z.configSections.sections {
+		resp[name] = redact(reflect.ValueOf(section))
+	}
+	z.configSections.Unlock()
+
+	w.Header().Set(HeaderCacheControl, "no-cache, no-store, must-revalidate")
+	w.Header().Set(HeaderContentType, MIMEApplicationJSON)
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// maxRedactDepth guards against cyclic data structures when redacting configuration.
+const maxRedactDepth = 32
+
+var (
+	secretType        = reflect.TypeFor[Secret]()
+	durationType      = reflect.TypeFor[time.Duration]()
+	timeType          = reflect.TypeFor[time.Time]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+)
+
+// redact converts the value into a representation that can be encoded as JSON with sensitive values
+// replaced.
+func redact(v reflect.Value) any {
+	return redactDepth(v, 0)
+}
+
+func redactDepth(v reflect.Value, depth int) any {
+	if !v.IsValid() || depth > maxRedactDepth {
+		return nil
+	}
+
+	switch v.Type() {
+	case secretType:
+		return redactedValue
+	case durationType:
+		return time.Duration(v.Int()).String()
+	case timeType:
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return redactDepth(v.Elem(), depth+1)
+	case reflect.Struct:
+		// Types that know how to encode themselves are left alone, such as netip.Addr or uuid.UUID.
+		if v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType) {
+			return v.Interface()
+		}
+		out := make(map[string]any, v.NumField())
+		redactFields(v, out, false, depth)
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		out := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			if isSensitiveKey(key) {
+				out[key] = redactedValue
+				continue
+			}
+			out[key] = redactDepth(iter.Value(), depth+1)
+		}
+		return out
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		// Byte slices are encoded as base64 strings by encoding/json.
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		out := make([]any, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out[i] = redactDepth(v.Index(i), depth+1)
+		}
+		return out
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return v.Type().String()
+	default:
+		return v.Interface()
+	}
+}
+
+// redactFields adds the fields of the struct to out keyed by their JSON names, replacing the values
+// of sensitive fields, or of every field if sensitive is set. Like encoding/json, the fields of
+// embedded structs without a JSON name are added as if they were fields of the outer struct, where
+// the fields of the outer struct take precedence.
+func redactFields(v reflect.Value, out map[string]any, sensitive bool, depth int) {
+	var embedded []int
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" && isEmbeddedStruct(field) {
+			embedded = append(embedded, i)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if sensitive || field.Tag.Get("sensitive") == "true" || isSensitiveKey(field.Name) || isSensitiveKey(name) {
+			out[name] = redactedValue
+			continue
+		}
+		out[name] = redactDepth(v.Field(i), depth+1)
+	}
+
+	for _, i := range embedded {
+		field := v.Type().Field(i)
+		fv := v.Field(i)
+		if field.Type.Kind() == reflect.Pointer {
+			// encoding/json ignores embedded pointers to unexported types.
+			if !field.IsExported() || fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+		if depth+1 > maxRedactDepth {
+			continue
+		}
+		fields := make(map[string]any, fv.NumField())
+		redactFields(fv, fields, sensitive || field.Tag.Get("sensitive") == "true" || isSensitiveKey(field.Name), depth+1)
+		for name, value := range fields {
+			if _, ok := out[name]; !ok {
+				out[name] = value
+			}
+		}
+	}
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
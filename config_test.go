@@ -0,0 +1,133 @@
+package yuna
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type configDatabase struct {
+	Host     string `json:"host"`
+	Password string `json:"pass"`
+}
+
+type configAuth struct {
+	ClientID string `json:"clientId"`
+	Name     string `json:"name"`
+}
+
+type ConfigLimits struct {
+	MaxConns int `json:"maxConns"`
+}
+
+type configCredentials struct {
+	User string `json:"user"`
+}
+
+type appConfig struct {
+	Name     string            `json:"name"`
+	Port     int               `json:"port,omitempty"`
+	Internal string            `json:"-"`
+	APIKey   Secret            `json:"apiKey"`
+	Signing  string            `json:"signing" sensitive:"true"`
+	Timeout  time.Duration     `json:"timeout"`
+	Endpoint netip.Addr        `json:"endpoint"`
+	Cert     []byte            `json:"cert"`
+	Headers  map[string]string `json:"headers"`
+	DB       configDatabase    `json:"db"`
+	Replicas []configDatabase  `json:"replicas"`
+	hidden   string
+	configAuth
+	*ConfigLimits
+	configCredentials
+	Nested configAuth `json:"nested"`
+}
+
+func TestRedact(t *testing.T) {
+	cfg := &appConfig{
+		Name:     "orders",
+		Port:     8080,
+		Internal: "internal",
+		APIKey:   "key",
+		Signing:  "signing-key",
+		Timeout:  5 * time.Second,
+		Endpoint: netip.MustParseAddr("10.0.0.1"),
+		Cert:     []byte("cert"),
+		Headers:  map[string]string{"X-Tenant": "acme", "X-Auth-Token": "token"},
+		DB:       configDatabase{Host: "db", Password: "pw"},
+		Replicas: []configDatabase{{Host: "replica", Password: "pw"}},
+		hidden:   "hidden",
+		// The outer Name takes precedence over the embedded one.
+		configAuth:        configAuth{ClientID: "client", Name: "auth"},
+		ConfigLimits:      &ConfigLimits{MaxConns: 10},
+		configCredentials: configCredentials{User: "admin"},
+		Nested:            configAuth{ClientID: "nested"},
+	}
+
+	got, err := json.Marshal(redact(reflect.ValueOf(cfg)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotMap map[string]any
+	if err := json.Unmarshal(got, &gotMap); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{
+		"name":     "orders",
+		"port":     float64(8080),
+		"apiKey":   redactedValue,
+		"signing":  redactedValue,
+		"timeout":  "5s",
+		"endpoint": "10.0.0.1",
+		"cert":     "Y2VydA==",
+		"headers":  map[string]any{"X-Tenant": "acme", "X-Auth-Token": redactedValue},
+		"db":       map[string]any{"host": "db", "pass": redactedValue},
+		"replicas": []any{map[string]any{"host": "replica", "pass": redactedValue}},
+		"clientId": "client",
+		"maxConns": float64(10),
+		// The fields of an embedded struct whose name looks sensitive are all redacted.
+		"user":   redactedValue,
+		"nested": map[string]any{"clientId": "nested", "name": ""},
+	}
+	if !reflect.DeepEqual(gotMap, want) {
+		t.Errorf("redact() =\n%s\nwant\n%v", got, want)
+	}
+}
+
+func TestRedactNilEmbeddedPointer(t *testing.T) {
+	got := redact(reflect.ValueOf(appConfig{Name: "orders"})).(map[string]any)
+	if _, ok := got["maxConns"]; ok {
+		t.Errorf("redact() = %v, want no fields of a nil embedded pointer", got)
+	}
+	if _, ok := got["ConfigLimits"]; ok {
+		t.Errorf("redact() = %v, want embedded structs flattened", got)
+	}
+}
+
+func TestConfigHandler(t *testing.T) {
+	app := New()
+	app.RegisterConfig("orders", &appConfig{Name: "orders", APIKey: "key"})
+	app.RegisterConfig("yuna", "ignored")
+
+	rec := httptest.NewRecorder()
+	app.OperationsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+
+	var resp map[string]map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp["orders"]["name"] != "orders" || resp["orders"]["apiKey"] != redactedValue {
+		t.Errorf("orders section = %v", resp["orders"])
+	}
+	if len(resp["yuna"]) == 0 {
+		t.Errorf("yuna section = %v, want the effective configuration", resp["yuna"])
+	}
+}
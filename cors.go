@@ -0,0 +1,420 @@
+package yuna
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/jkratz55/yuna/log"
+)
+
+// CORSPolicy configures the Cross-Origin Resource Sharing (CORS) policy enforced by the CORS
+// middleware, which determines the origins allowed to call the API from a browser.
+type CORSPolicy struct {
+	// AllowedOrigins are the origins allowed to make cross-origin requests, such as
+	// "https://app.example.com". An origin may use a wildcard for the subdomains of a domain, such
+	// as "https://*.example.com", which matches https://app.example.com and
+	// https://eu.app.example.com but not https://example.com. The origin "*" allows any origin.
+	AllowedOrigins []string
+
+	// AllowedOriginPatterns are regular expressions matching the origins allowed to make
+	// cross-origin requests, in addition to AllowedOrigins. The patterns must match the whole
+	// origin, such as `https://pr-\d+\.preview\.example\.com`.
+	AllowedOriginPatterns []string
+
+	// AllowedMethods are the methods allowed in cross-origin requests. The default is GET, HEAD,
+	// POST, PUT, PATCH, and DELETE.
+	AllowedMethods []string
+
+	// AllowedHeaders are the request headers allowed in cross-origin requests, in addition to the
+	// headers browsers always allow, such as Accept and Content-Language. The header "*" allows any
+	// header. The default is Authorization, Content-Type, and X-Request-Id.
+	AllowedHeaders []string
+
+	// ExposedHeaders are the response headers the browser exposes to the caller, in addition to the
+	// headers it always exposes, such as Content-Type and Cache-Control.
+	ExposedHeaders []string
+
+	// AllowCredentials allows cross-origin requests to include cookies and the Authorization
+	// header. It cannot be combined with the origin "*".
+	AllowCredentials bool
+
+	// MaxAge is how long the browser may cache the result of a preflight request. Browsers limit
+	// it, Chrome to 2 hours and Firefox to 24 hours. If zero, the Access-Control-Max-Age header
+	// isn't sent and the browser uses its default of 5 seconds.
+	MaxAge time.Duration
+}
+
+// defaultCORSMethods are the methods allowed in cross-origin requests unless set by the policy.
+var defaultCORSMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// defaultCORSHeaders are the request headers allowed in cross-origin requests unless set by the
+// policy.
+var defaultCORSHeaders = []string{
+	HeaderAuthorization,
+	HeaderContentType,
+	HeaderXRequestID,
+}
+
+// CORS returns an HTTP middleware enforcing the Cross-Origin Resource Sharing policy.
+//
+// Preflight requests from allowed origins are answered with 204 No Content, without calling the
+// handler, even if no OPTIONS route is registered for the path. Cross-origin requests from allowed
+// origins receive the Access-Control-Allow-Origin header and the other headers configured by the
+// policy. Requests from origins that aren't allowed, and preflight requests for methods or headers
+// that aren't allowed, receive a 403 Forbidden problem and are logged at debug level. Requests
+// without an Origin header and same-origin requests are passed to the handler as is.
+//
+// The policy can be applied to all routes using WithCORS, or to specific routes using Router.With
+// or Router.Group, which allows routes to have different policies. A policy set with WithCORS
+// handles the requests for all routes, so routes with their own policy shouldn't also be covered
+// by WithCORS. Preflight requests for routes with their own policy are answered by an OPTIONS
+// route the router registers for the route pattern, without running the middleware or handlers of
+// the routes, unless the application registers an OPTIONS route for the pattern itself. Preflight
+// requests for methods of the route without a CORS policy receive 405 Method Not Allowed.
+//
+//	api := router.With(yuna.CORS(yuna.CORSPolicy{
+//		AllowedOrigins:   []string{"https://*.example.com"},
+//		AllowCredentials: true,
+//		MaxAge:           time.Hour,
+//	}))
+//	api.Get("/orders", listOrders)
+//
+// CORS panics if the policy allows credentials for any origin, or if an origin pattern isn't a
+// valid regular expression.
+func CORS(policy CORSPolicy) HttpMiddleware {
+	c := newCORS(policy)
+	return func(next http.Handler) http.Handler {
+		return &corsHandler{cors: c, next: next}
+	}
+}
+
+// corsHandler is the http.Handler returned by the CORS middleware. Its type allows Mux to find the
+// policy applied to routes with Router.With or Router.Group, see corsPolicy.
+type corsHandler struct {
+	cors *cors
+	next http.Handler
+}
+
+func (h *corsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.cors.serve(w, r, h.next)
+}
+
+// corsPolicy returns the CORS policy applied by the middleware, or nil if it isn't the CORS
+// middleware. The middleware is applied to a handler which is never called.
+func corsPolicy(middleware func(http.Handler) http.Handler) *cors {
+	if h, ok := middleware(http.NotFoundHandler()).(*corsHandler); ok {
+		return h.cors
+	}
+	return nil
+}
+
+type cors struct {
+	anyOrigin        bool
+	origins          []string
+	subdomains       []string
+	patterns         []*regexp.Regexp
+	methods          []string
+	anyHeader        bool
+	headers          []string
+	exposedHeaders   string
+	allowCredentials bool
+	maxAge           string
+}
+
+func newCORS(policy CORSPolicy) *cors {
+	c := &cors{
+		methods:          defaultCORSMethods,
+		exposedHeaders:   strings.Join(policy.ExposedHeaders, ", "),
+		allowCredentials: policy.AllowCredentials,
+	}
+
+	for _, origin := range policy.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		switch {
+		case origin == "*":
+			c.anyOrigin = true
+		case strings.Contains(origin, "://*."):
+			// The wildcard is kept as the prefix of the domain, so *.example.com doesn't match
+			// badexample.com.
+			c.subdomains = append(c.subdomains, strings.Replace(origin, "://*.", "://.", 1))
+		case origin != "":
+			c.origins = append(c.origins, origin)
+		}
+	}
+	for _, pattern := range policy.AllowedOriginPatterns {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			panic(fmt.Sprintf("invalid CORS origin pattern %q: %s", pattern, err))
+		}
+		c.patterns = append(c.patterns, re)
+	}
+	if c.anyOrigin && c.allowCredentials {
+		panic("CORS policy cannot allow credentials for any origin")
+	}
+
+	if len(policy.AllowedMethods) > 0 {
+		c.methods = make([]string, 0, len(policy.AllowedMethods))
+		for _, method := range policy.AllowedMethods {
+			c.methods = append(c.methods, strings.ToUpper(strings.TrimSpace(method)))
+		}
+	}
+	headers := policy.AllowedHeaders
+	if len(headers) == 0 {
+		headers = defaultCORSHeaders
+	}
+	for _, header := range headers {
+		header = strings.TrimSpace(header)
+		if header == "*" {
+			c.anyHeader = true
+			continue
+		}
+		c.headers = append(c.headers, http.CanonicalHeaderKey(header))
+	}
+	if policy.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(policy.MaxAge.Seconds()))
+	}
+	return c
+}
+
+func (c *cors) serve(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	origin := r.Header.Get(HeaderOrigin)
+	preflight := isPreflight(r)
+	if origin == "" {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	h := w.Header()
+	addVary(h, HeaderOrigin)
+	if !preflight && isSameOrigin(r, origin) {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	logger := log.LoggerFromCtx(r.Context())
+	if !c.originAllowed(origin) {
+		logger.Debug("Rejected CORS request from disallowed origin",
+			log.String("origin", origin),
+			log.Bool("preflight", preflight))
+		Forbidden().
+			SetDetail(fmt.Sprintf("The origin %s isn't allowed to access this resource.", origin)).
+			ServeHTTP(w, r)
+		return
+	}
+
+	if c.anyOrigin {
+		h.Set(HeaderAccessControlAllowOrigin, "*")
+	} else {
+		h.Set(HeaderAccessControlAllowOrigin, origin)
+	}
+	if c.allowCredentials {
+		h.Set(HeaderAccessControlAllowCredentials, "true")
+	}
+
+	if !preflight {
+		if c.exposedHeaders != "" {
+			h.Set(HeaderAccessControlExposeHeaders, c.exposedHeaders)
+		}
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	addVary(h, HeaderAccessControlRequestMethod)
+	addVary(h, HeaderAccessControlRequestHeaders)
+
+	method := r.Header.Get(HeaderAccessControlRequestMethod)
+	if !slices.Contains(c.methods, method) {
+		logger.Debug("Rejected CORS preflight request for disallowed method",
+			log.String("origin", origin),
+			log.String("method", method))
+		Forbidden().
+			SetDetail(fmt.Sprintf("The method %s isn't allowed in cross-origin requests.", method)).
+			ServeHTTP(w, r)
+		return
+	}
+
+	headers := requestedHeaders(r)
+	for _, header := range headers {
+		if !c.headerAllowed(header) {
+			logger.Debug("Rejected CORS preflight request for disallowed header",
+				log.String("origin", origin),
+				log.String("header", header))
+			Forbidden().
+				SetDetail(fmt.Sprintf("The header %s isn't allowed in cross-origin requests.", header)).
+				ServeHTTP(w, r)
+			return
+		}
+	}
+
+	h.Set(HeaderAccessControlAllowMethods, method)
+	if len(headers) > 0 {
+		h.Set(HeaderAccessControlAllowHeaders, strings.Join(headers, ", "))
+	}
+	if c.maxAge != "" {
+		h.Set(HeaderAccessControlMaxAge, c.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// originAllowed reports whether the policy allows requests from the origin.
+func (c *cors) originAllowed(origin string) bool {
+	if c.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if slices.Contains(c.origins, origin) {
+		return true
+	}
+	for _, subdomain := range c.subdomains {
+		scheme, domain, _ := strings.Cut(subdomain, "://")
+		if rest, ok := strings.CutPrefix(origin, scheme+"://"); ok && len(rest) > len(domain) &&
+			strings.HasSuffix(rest, domain) {
+			return true
+		}
+	}
+	for _, re := range c.patterns {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerAllowed reports whether the policy allows the request header. Headers browsers consider
+// safe, known as CORS-safelisted request headers, are always allowed.
+func (c *cors) headerAllowed(header string) bool {
+	if c.anyHeader || slices.Contains(c.headers, header) {
+		return true
+	}
+	switch header {
+	case HeaderAccept, HeaderAcceptLanguage, HeaderContentLanguage, HeaderContentType:
+		return true
+	}
+	return false
+}
+
+// requestedHeaders returns the canonical names of the headers listed by the
+// Access-Control-Request-Headers header of a preflight request.
+func requestedHeaders(r *http.Request) []string {
+	var headers []string
+	for _, value := range r.Header.Values(HeaderAccessControlRequestHeaders) {
+		for header := range strings.SplitSeq(value, ",") {
+			if header = strings.TrimSpace(header); header != "" {
+				headers = append(headers, http.CanonicalHeaderKey(header))
+			}
+		}
+	}
+	return headers
+}
+
+// isPreflight reports whether the request is a CORS preflight request, sent by browsers before a
+// cross-origin request to check it is allowed.
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get(HeaderOrigin) != "" &&
+		r.Header.Get(HeaderAccessControlRequestMethod) != ""
+}
+
+// isSameOrigin reports whether the origin is the host the request was sent to. Browsers send the
+// Origin header with same-origin requests using methods other than GET and HEAD.
+func isSameOrigin(r *http.Request, origin string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, r.Host)
+}
+
+// preflightRoutes are the OPTIONS routes answering preflight requests for the routes of a router
+// which have a CORS policy applied using Router.With or Router.Group, keyed by route pattern.
+// Without them chi would answer preflight requests with 405 Method Not Allowed, since there is no
+// OPTIONS route, and the middleware of the route would never see them.
+type preflightRoutes map[string]*preflightRoute
+
+// add registers the CORS policy of the route with the method and pattern, registering the OPTIONS
+// route with the router the first time a policy is added for the pattern.
+func (p preflightRoutes) add(router chi.Router, method, pattern string, policy *cors) {
+	route, ok := p[pattern]
+	if !ok {
+		route = &preflightRoute{router: router, policies: make(map[string]*cors)}
+		p[pattern] = route
+		router.Method(http.MethodOptions, pattern, route)
+	}
+	route.policies[method] = policy
+}
+
+// explicit records that an OPTIONS route was registered for the pattern by the application, which
+// then answers preflight requests itself.
+func (p preflightRoutes) explicit(pattern string) {
+	if _, ok := p[pattern]; !ok {
+		p[pattern] = &preflightRoute{}
+	}
+}
+
+// preflightRoute answers preflight requests for a route pattern using the CORS policy of the route
+// of the requested method. It is registered without the middleware of the routes, so preflight
+// requests never reach their middleware or handlers.
+type preflightRoute struct {
+	router   chi.Router
+	policies map[string]*cors
+}
+
+func (p *preflightRoute) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	policy, ok := p.policies[r.Header.Get(HeaderAccessControlRequestMethod)]
+	if !ok || !isPreflight(r) {
+		p.methodNotAllowed(w, r)
+		return
+	}
+	policy.serve(w, r, http.HandlerFunc(p.methodNotAllowed))
+}
+
+// routeMethods are the methods reported in the Allow header of a preflight request that isn't
+// answered by a CORS policy.
+var routeMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodConnect,
+	http.MethodTrace,
+}
+
+// methodNotAllowed answers OPTIONS requests not answered by a CORS policy with the 405 Method Not
+// Allowed chi would have sent, using the handler set by WithMethodNotAllowedHandler, and lists the
+// methods of the route in the Allow header.
+func (p *preflightRoute) methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	path := ""
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		path = rctx.RoutePath
+	}
+	if path == "" {
+		path = r.URL.RawPath
+	}
+	if path == "" {
+		path = r.URL.Path
+	}
+	for _, method := range routeMethods {
+		if p.router.Match(chi.NewRouteContext(), method, path) {
+			w.Header().Add(HeaderAllow, method)
+		}
+	}
+
+	if mux, ok := p.router.(*chi.Mux); ok {
+		mux.MethodNotAllowedHandler().ServeHTTP(w, r)
+		return
+	}
+	MethodNotAllowed().ServeHTTP(w, r)
+}
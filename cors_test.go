@@ -0,0 +1,426 @@
+package yuna
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestCORS(t *testing.T) {
+	policy := CORSPolicy{
+		AllowedOrigins:        []string{"https://app.example.com", "https://*.example.org"},
+		AllowedOriginPatterns: []string{`https://pr-\d+\.preview\.example\.net`},
+		AllowedMethods:        []string{"get", "POST"},
+		AllowedHeaders:        []string{"X-Custom"},
+		ExposedHeaders:        []string{"X-Total-Count"},
+		AllowCredentials:      true,
+		MaxAge:                time.Hour,
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		header     map[string]string
+		wantStatus int
+		wantCalled bool
+		wantHeader map[string]string
+	}{
+		{
+			name:       "no origin",
+			method:     http.MethodGet,
+			wantStatus: http.StatusOK,
+			wantCalled: true,
+			wantHeader: map[string]string{HeaderAccessControlAllowOrigin: "", HeaderVary: ""},
+		},
+		{
+			name:       "same origin",
+			method:     http.MethodPost,
+			header:     map[string]string{HeaderOrigin: "http://example.com"},
+			wantStatus: http.StatusOK,
+			wantCalled: true,
+			wantHeader: map[string]string{HeaderAccessControlAllowOrigin: "", HeaderVary: HeaderOrigin},
+		},
+		{
+			name:       "allowed origin",
+			method:     http.MethodGet,
+			header:     map[string]string{HeaderOrigin: "https://app.example.com"},
+			wantStatus: http.StatusOK,
+			wantCalled: true,
+			wantHeader: map[string]string{
+				HeaderAccessControlAllowOrigin:      "https://app.example.com",
+				HeaderAccessControlAllowCredentials: "true",
+				HeaderAccessControlExposeHeaders:    "X-Total-Count",
+				HeaderVary:                          HeaderOrigin,
+			},
+		},
+		{
+			name:       "origin is case insensitive",
+			method:     http.MethodGet,
+			header:     map[string]string{HeaderOrigin: "https://APP.example.com"},
+			wantStatus: http.StatusOK,
+			wantCalled: true,
+			wantHeader: map[string]string{HeaderAccessControlAllowOrigin: "https://APP.example.com"},
+		},
+		{
+			name:       "wildcard subdomain",
+			method:     http.MethodGet,
+			header:     map[string]string{HeaderOrigin: "https://eu.app.example.org"},
+			wantStatus: http.StatusOK,
+			wantCalled: true,
+			wantHeader: map[string]string{HeaderAccessControlAllowOrigin: "https://eu.app.example.org"},
+		},
+		{
+			name:       "wildcard doesn't match the domain itself",
+			method:     http.MethodGet,
+			header:     map[string]string{HeaderOrigin: "https://example.org"},
+			wantStatus: http.StatusForbidden,
+			wantHeader: map[string]string{HeaderAccessControlAllowOrigin: "", HeaderVary: HeaderOrigin},
+		},
+		{
+			name:       "wildcard doesn't match a suffix of the domain",
+			method:     http.MethodGet,
+			header:     map[string]string{HeaderOrigin: "https://badexample.org"},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "wildcard requires the scheme",
+			method:     http.MethodGet,
+			header:     map[string]string{HeaderOrigin: "http://app.example.org"},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "origin pattern",
+			method:     http.MethodGet,
+			header:     map[string]string{HeaderOrigin: "https://pr-42.preview.example.net"},
+			wantStatus: http.StatusOK,
+			wantCalled: true,
+			wantHeader: map[string]string{HeaderAccessControlAllowOrigin: "https://pr-42.preview.example.net"},
+		},
+		{
+			name:       "origin pattern matches the whole origin",
+			method:     http.MethodGet,
+			header:     map[string]string{HeaderOrigin: "https://pr-42.preview.example.net.evil.com"},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "disallowed origin",
+			method:     http.MethodGet,
+			header:     map[string]string{HeaderOrigin: "https://evil.example"},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "preflight",
+			method: http.MethodOptions,
+			header: map[string]string{
+				HeaderOrigin:                      "https://app.example.com",
+				HeaderAccessControlRequestMethod:  http.MethodPost,
+				HeaderAccessControlRequestHeaders: "x-custom, content-type",
+			},
+			wantStatus: http.StatusNoContent,
+			wantHeader: map[string]string{
+				HeaderAccessControlAllowOrigin:      "https://app.example.com",
+				HeaderAccessControlAllowCredentials: "true",
+				HeaderAccessControlAllowMethods:     http.MethodPost,
+				HeaderAccessControlAllowHeaders:     "X-Custom, Content-Type",
+				HeaderAccessControlMaxAge:           "3600",
+				HeaderAccessControlExposeHeaders:    "",
+			},
+		},
+		{
+			name:   "preflight for disallowed method",
+			method: http.MethodOptions,
+			header: map[string]string{
+				HeaderOrigin:                     "https://app.example.com",
+				HeaderAccessControlRequestMethod: http.MethodDelete,
+			},
+			wantStatus: http.StatusForbidden,
+			wantHeader: map[string]string{HeaderAccessControlAllowMethods: ""},
+		},
+		{
+			name:   "preflight for disallowed header",
+			method: http.MethodOptions,
+			header: map[string]string{
+				HeaderOrigin:                      "https://app.example.com",
+				HeaderAccessControlRequestMethod:  http.MethodGet,
+				HeaderAccessControlRequestHeaders: "X-Other",
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "preflight from disallowed origin",
+			method: http.MethodOptions,
+			header: map[string]string{
+				HeaderOrigin:                     "https://evil.example",
+				HeaderAccessControlRequestMethod: http.MethodGet,
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "options without request method isn't a preflight",
+			method:     http.MethodOptions,
+			header:     map[string]string{HeaderOrigin: "https://app.example.com"},
+			wantStatus: http.StatusOK,
+			wantCalled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := CORS(policy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			}))
+
+			req := httptest.NewRequest(tt.method, "http://example.com/orders", nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if called != tt.wantCalled {
+				t.Errorf("handler called = %t, want %t", called, tt.wantCalled)
+			}
+			for k, v := range tt.wantHeader {
+				if got := rec.Header().Get(k); got != v {
+					t.Errorf("%s = %q, want %q", k, got, v)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	handler := CORS(CORSPolicy{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"*"}})(
+		http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodOptions, "/", nil)
+	req.Header.Set(HeaderOrigin, "https://anything.example")
+	req.Header.Set(HeaderAccessControlRequestMethod, http.MethodPut)
+	req.Header.Set(HeaderAccessControlRequestHeaders, "X-Anything")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if got := rec.Header().Get(HeaderAccessControlAllowOrigin); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+	}
+	if got := rec.Header().Get(HeaderAccessControlAllowCredentials); got != "" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want none", got)
+	}
+	want := []string{HeaderOrigin, HeaderAccessControlRequestMethod, HeaderAccessControlRequestHeaders}
+	if got := rec.Header().Values(HeaderVary); !slices.Equal(got, want) {
+		t.Errorf("Vary = %q, want %q", got, want)
+	}
+}
+
+func TestCORSInvalidPolicy(t *testing.T) {
+	tests := map[string]CORSPolicy{
+		"credentials for any origin": {AllowedOrigins: []string{"*"}, AllowCredentials: true},
+		"invalid origin pattern":     {AllowedOriginPatterns: []string{"https://(.example.com"}},
+	}
+	for name, policy := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("CORS() didn't panic")
+				}
+			}()
+			CORS(policy)
+		})
+	}
+}
+
+func TestCORSPreflightRoutes(t *testing.T) {
+	policy := CORSPolicy{AllowedOrigins: []string{"https://app.example.com"}}
+	teapot := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	var called []string
+	handler := func(name string) HandlerFunc {
+		return func(*Request) Responder {
+			called = append(called, name)
+			return NoContent()
+		}
+	}
+
+	app := New(WithMethodNotAllowedHandler(teapot))
+	app.With(Authenticated(), MaxBodySize(1), CORS(policy)).Delete("/items/{id}", handler("delete item"))
+	app.Get("/items/{id}", handler("get item"))
+	app.Group(func(r Router) {
+		r.Use(Authenticated())
+		r.Use(CORS(policy))
+		r.Post("/orders", handler("create order"))
+	})
+	app.With(CORS(policy)).Put("/explicit", handler("put explicit"))
+	app.Options("/explicit", handler("options explicit"))
+	app.Route("/api", func(r Router) {
+		r.With(CORS(policy)).Patch("/users/{id:[0-9]+}", handler("patch user"))
+	})
+	app.Get("/plain", handler("get plain"))
+
+	sub := chi.NewRouter()
+	sub.Delete("/things/{id}", func(w http.ResponseWriter, r *http.Request) {
+		called = append(called, "delete mounted")
+		w.WriteHeader(http.StatusNoContent)
+	})
+	app.Mount("/mounted", sub)
+
+	tests := []struct {
+		name       string
+		path       string
+		origin     string
+		method     string
+		wantStatus int
+		wantOrigin string
+		wantAllow  []string
+		wantCalled []string
+	}{
+		{
+			name:       "route with middleware before the policy",
+			path:       "/items/1",
+			method:     http.MethodDelete,
+			wantStatus: http.StatusNoContent,
+			wantOrigin: "https://app.example.com",
+		},
+		{
+			name:       "disallowed origin",
+			path:       "/items/1",
+			origin:     "https://evil.example",
+			method:     http.MethodDelete,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "method of the route without a policy",
+			path:       "/items/1",
+			method:     http.MethodGet,
+			wantStatus: http.StatusTeapot,
+			wantAllow:  []string{http.MethodGet, http.MethodDelete},
+		},
+		{
+			name:       "method without a route",
+			path:       "/items/1",
+			method:     http.MethodPut,
+			wantStatus: http.StatusTeapot,
+			wantAllow:  []string{http.MethodGet, http.MethodDelete},
+		},
+		{
+			name:       "group",
+			path:       "/orders",
+			method:     http.MethodPost,
+			wantStatus: http.StatusNoContent,
+			wantOrigin: "https://app.example.com",
+		},
+		{
+			name:       "sub router",
+			path:       "/api/users/7",
+			method:     http.MethodPatch,
+			wantStatus: http.StatusNoContent,
+			wantOrigin: "https://app.example.com",
+		},
+		{
+			name:       "sub router with path not matching the route",
+			path:       "/api/users/abc",
+			method:     http.MethodPatch,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "explicit options route",
+			path:       "/explicit",
+			method:     http.MethodPut,
+			wantStatus: http.StatusNoContent,
+			wantCalled: []string{"options explicit"},
+		},
+		{
+			name:       "route without a policy",
+			path:       "/plain",
+			method:     http.MethodGet,
+			wantStatus: http.StatusTeapot,
+		},
+		{
+			name:       "mounted handler",
+			path:       "/mounted/things/1",
+			origin:     "https://evil.example",
+			method:     http.MethodDelete,
+			wantStatus: http.StatusTeapot,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called = nil
+			origin := tt.origin
+			if origin == "" {
+				origin = "https://app.example.com"
+			}
+			req := httptest.NewRequest(http.MethodOptions, tt.path, nil)
+			req.Header.Set(HeaderOrigin, origin)
+			req.Header.Set(HeaderAccessControlRequestMethod, tt.method)
+			rec := httptest.NewRecorder()
+			app.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get(HeaderAccessControlAllowOrigin); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if tt.wantAllow != nil && !slices.Equal(rec.Header().Values(HeaderAllow), tt.wantAllow) {
+				t.Errorf("Allow = %q, want %q", rec.Header().Values(HeaderAllow), tt.wantAllow)
+			}
+			if !slices.Equal(called, tt.wantCalled) {
+				t.Errorf("handlers called = %q, want %q", called, tt.wantCalled)
+			}
+		})
+	}
+}
+
+func TestCORSRoutePolicyAppliesToRequests(t *testing.T) {
+	app := New()
+	app.With(CORS(CORSPolicy{AllowedOrigins: []string{"https://app.example.com"}})).
+		Get("/orders", func(*Request) Responder { return Ok("orders") })
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set(HeaderOrigin, "https://app.example.com")
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := rec.Header().Get(HeaderAccessControlAllowOrigin); got != "https://app.example.com" {
+		t.Errorf("Access-Control-Allow-Origin = %q", got)
+	}
+}
+
+func TestWithCORS(t *testing.T) {
+	var called bool
+	app := New(WithCORS(CORSPolicy{AllowedOrigins: []string{"https://app.example.com"}}))
+	app.Delete("/items/{id}", func(*Request) Responder {
+		called = true
+		return NoContent()
+	})
+
+	req := httptest.NewRequest(http.MethodOptions, "/items/1", nil)
+	req.Header.Set(HeaderOrigin, "https://app.example.com")
+	req.Header.Set(HeaderAccessControlRequestMethod, http.MethodDelete)
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if called {
+		t.Error("handler called for preflight request")
+	}
+}
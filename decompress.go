@@ -0,0 +1,239 @@
+package yuna
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// A DecompressOption configures the Decompress middleware.
+type DecompressOption func(c *decompressConfig)
+
+type decompressConfig struct {
+	maxSize int64
+}
+
+// DecompressMaxSize limits the size of decompressed request bodies to n bytes, protecting the
+// server against small compressed bodies expanding to huge ones, known as zip bombs. Reading more
+// than n bytes returns an *http.MaxBytesError, which Request.DecodeAndValidate and Typed handlers
+// convert to a 413 Content Too Large problem. The default is 10 MiB.
+func DecompressMaxSize(n int64) DecompressOption {
+	if n <= 0 {
+		panic("decompressed request body size limit must be greater than zero")
+	}
+	return func(c *decompressConfig) {
+		c.maxSize = n
+	}
+}
+
+// decompressEncodings are the content codings of request bodies supported by Decompress.
+var decompressEncodings = []string{"gzip", "deflate"}
+
+var (
+	gzipReaders sync.Pool
+	zlibReaders sync.Pool
+)
+
+// Decompress returns an HTTP middleware decompressing request bodies encoded with gzip or deflate,
+// as declared by the Content-Encoding header, so Request.Decode and Bind read the decompressed
+// body. The Content-Encoding and Content-Length headers are removed from decompressed requests.
+//
+// Requests with any other content coding, or with more than one, receive a 415 Unsupported Media
+// Type problem listing the supported codings in the Accept-Encoding header, and requests whose body
+// isn't valid for its coding receive a 400 Bad Request problem. The size of decompressed bodies is
+// always limited, see DecompressMaxSize. Limits set with MaxBodySize or WithMaxRequestBodySize
+// earlier in the chain apply to the compressed body.
+//
+// The ratio of the decompressed size to the compressed size of request bodies is recorded by the
+// http.server.request.body.compression_ratio metric.
+func Decompress(opts ...DecompressOption) HttpMiddleware {
+	conf := &decompressConfig{
+		maxSize: 10 << 20,
+	}
+	for _, opt := range opts {
+		opt(conf)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			coding, ok := requestContentCoding(r.Header)
+			if ok && (coding == "" || r.Body == nil || r.Body == http.NoBody) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !ok || (coding != "gzip" && coding != "deflate") {
+				w.Header().Set(HeaderAcceptEncoding, strings.Join(decompressEncodings, ", "))
+				UnsupportedMediaType().
+					SetDetail(fmt.Sprintf("The content coding of the request body isn't supported, supported codings are %s.",
+						strings.Join(decompressEncodings, ", "))).
+					ServeHTTP(w, r)
+				return
+			}
+
+			compressed := &countingReader{r: r.Body}
+			dec, err := newDecompressor(coding, compressed)
+			if errors.Is(err, io.EOF) {
+				// An empty body is left to the handler, just like an empty uncompressed body.
+				r.Body = http.NoBody
+				r.Header.Del(HeaderContentEncoding)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err != nil {
+				BadRequest(nil).
+					SetDetail(fmt.Sprintf("The request body isn't valid %s content.", coding)).
+					SetError(err).
+					ServeHTTP(w, r)
+				return
+			}
+
+			body := &decompressBody{
+				ReadCloser: http.MaxBytesReader(w, dec, conf.maxSize),
+				body:       r.Body,
+				dec:        dec,
+				coding:     coding,
+				compressed: compressed,
+			}
+			defer body.release()
+
+			r.Body = body
+			r.Header.Del(HeaderContentEncoding)
+			r.Header.Del(HeaderContentLength)
+			r.ContentLength = -1
+			next.ServeHTTP(w, r)
+
+			if body.decompressed > 0 {
+				if rec := findDecompressionRecorder(w); rec != nil {
+					rec.RecordDecompression(compressed.n, body.decompressed)
+				}
+			}
+		})
+	}
+}
+
+// requestContentCoding returns the content coding of the request body, which is empty if the body
+// isn't encoded, and false if the body is encoded with more than one coding.
+func requestContentCoding(h http.Header) (string, bool) {
+	coding := ""
+	for _, value := range h.Values(HeaderContentEncoding) {
+		for c := range strings.SplitSeq(value, ",") {
+			c = strings.ToLower(strings.TrimSpace(c))
+			switch {
+			case c == "" || c == "identity":
+			case coding != "":
+				return "", false
+			default:
+				coding = c
+			}
+		}
+	}
+	// x-gzip is an alias of gzip, see RFC 9110.
+	if coding == "x-gzip" {
+		coding = "gzip"
+	}
+	return coding, true
+}
+
+// newDecompressor returns a reader decompressing r, reusing a pooled reader if possible. Both
+// gzip.NewReader and zlib.NewReader read the header of the compressed stream, so an invalid stream
+// is detected immediately. A pooled reader is put back if the stream is invalid, so a series of
+// malformed requests doesn't drain the pool.
+func newDecompressor(coding string, r io.Reader) (io.ReadCloser, error) {
+	switch coding {
+	case "gzip":
+		if zr, ok := gzipReaders.Get().(*gzip.Reader); ok {
+			if err := zr.Reset(r); err != nil {
+				gzipReaders.Put(zr)
+				return nil, err
+			}
+			return zr, nil
+		}
+		return gzip.NewReader(r)
+	default:
+		if zr, ok := zlibReaders.Get().(io.ReadCloser); ok {
+			if err := zr.(zlib.Resetter).Reset(r, nil); err != nil {
+				zlibReaders.Put(zr)
+				return nil, err
+			}
+			return zr, nil
+		}
+		return zlib.NewReader(r)
+	}
+}
+
+// decompressBody is a decompressed request body. Closing it closes the original body.
+type decompressBody struct {
+	io.ReadCloser
+	body         io.ReadCloser
+	dec          io.ReadCloser
+	coding       string
+	compressed   *countingReader
+	decompressed uint64
+	released     bool
+}
+
+func (b *decompressBody) Read(p []byte) (int, error) {
+	if b.released {
+		return 0, http.ErrBodyReadAfterClose
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.decompressed += uint64(n)
+	return n, err
+}
+
+func (b *decompressBody) Close() error {
+	return b.body.Close()
+}
+
+// release returns the decompressor to its pool once the handler returned. Handlers must not read the
+// body after returning, as documented by http.Handler.
+func (b *decompressBody) release() {
+	if b.released {
+		return
+	}
+	b.released = true
+	_ = b.dec.Close()
+	switch b.coding {
+	case "gzip":
+		gzipReaders.Put(b.dec)
+	default:
+		zlibReaders.Put(b.dec)
+	}
+}
+
+// decompressionRecorder is implemented by the ResponseWriter recording the request metrics, to
+// report the compression ratio of request bodies.
+type decompressionRecorder interface {
+	RecordDecompression(compressed, decompressed uint64)
+}
+
+// findDecompressionRecorder unwraps the ResponseWriter until it finds a decompressionRecorder.
+func findDecompressionRecorder(w http.ResponseWriter) decompressionRecorder {
+	for {
+		if rec, ok := w.(decompressionRecorder); ok {
+			return rec
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = u.Unwrap()
+	}
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n uint64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += uint64(n)
+	return n, err
+}
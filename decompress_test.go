@@ -0,0 +1,225 @@
+package yuna
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func deflated(t *testing.T, s string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	const payload = `{"name":"Ann","email":"ann@example.com"}`
+	large := `{"name":"` + strings.Repeat("a", 1024) + `"}`
+
+	tests := []struct {
+		name       string
+		encoding   []string
+		body       []byte
+		opts       []DecompressOption
+		wantStatus int
+		wantBody   string
+		wantAccept string
+		wantDetail string
+	}{
+		{
+			name:       "gzip",
+			encoding:   []string{"gzip"},
+			body:       gzipped(t, payload),
+			wantStatus: http.StatusOK,
+			wantBody:   payload,
+		},
+		{
+			name:       "x-gzip",
+			encoding:   []string{"X-Gzip"},
+			body:       gzipped(t, payload),
+			wantStatus: http.StatusOK,
+			wantBody:   payload,
+		},
+		{
+			name:       "deflate",
+			encoding:   []string{"deflate"},
+			body:       deflated(t, payload),
+			wantStatus: http.StatusOK,
+			wantBody:   payload,
+		},
+		{
+			name:       "identity",
+			encoding:   []string{"identity"},
+			body:       []byte(payload),
+			wantStatus: http.StatusOK,
+			wantBody:   payload,
+		},
+		{
+			name:       "not encoded",
+			body:       []byte(payload),
+			wantStatus: http.StatusOK,
+			wantBody:   payload,
+		},
+		{
+			name:       "empty encoded body",
+			encoding:   []string{"gzip"},
+			body:       []byte{},
+			wantStatus: http.StatusOK,
+			wantBody:   "",
+		},
+		{
+			name:       "unknown coding",
+			encoding:   []string{"br"},
+			body:       []byte(payload),
+			wantStatus: http.StatusUnsupportedMediaType,
+			wantAccept: "gzip, deflate",
+		},
+		{
+			name:       "multiple codings",
+			encoding:   []string{"deflate, gzip"},
+			body:       gzipped(t, string(deflated(t, payload))),
+			wantStatus: http.StatusUnsupportedMediaType,
+			wantAccept: "gzip, deflate",
+		},
+		{
+			name:       "multiple header values",
+			encoding:   []string{"gzip", "gzip"},
+			body:       gzipped(t, string(gzipped(t, payload))),
+			wantStatus: http.StatusUnsupportedMediaType,
+			wantAccept: "gzip, deflate",
+		},
+		{
+			name:       "invalid gzip stream",
+			encoding:   []string{"gzip"},
+			body:       []byte(payload),
+			wantStatus: http.StatusBadRequest,
+			wantDetail: "The request body isn't valid gzip content.",
+		},
+		{
+			name:       "invalid deflate stream",
+			encoding:   []string{"deflate"},
+			body:       gzipped(t, payload),
+			wantStatus: http.StatusBadRequest,
+			wantDetail: "The request body isn't valid deflate content.",
+		},
+		{
+			name:       "decompressed size limit",
+			encoding:   []string{"gzip"},
+			body:       gzipped(t, large),
+			opts:       []DecompressOption{DecompressMaxSize(512)},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "within decompressed size limit",
+			encoding:   []string{"gzip"},
+			body:       gzipped(t, large),
+			opts:       []DecompressOption{DecompressMaxSize(2048)},
+			wantStatus: http.StatusOK,
+			wantBody:   large,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := Decompress(tt.opts...)(wrapFn(func(r *Request) Responder {
+				if enc := r.Header(HeaderContentEncoding); enc != "" && enc != "identity" {
+					t.Errorf("%s = %q in handler", HeaderContentEncoding, enc)
+				}
+				var body json.RawMessage
+				if hasBody(r.raw) {
+					if problem := r.DecodeAndValidate(&body); problem != nil {
+						return problem
+					}
+				}
+				got = string(body)
+				return Ok(nil)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/users", bytes.NewReader(tt.body))
+			req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+			for _, enc := range tt.encoding {
+				req.Header.Add(HeaderContentEncoding, enc)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code == http.StatusOK && got != tt.wantBody {
+				t.Errorf("handler read %q, want %q", got, tt.wantBody)
+			}
+			if got := rec.Header().Get(HeaderAcceptEncoding); got != tt.wantAccept {
+				t.Errorf("%s = %q, want %q", HeaderAcceptEncoding, got, tt.wantAccept)
+			}
+			if tt.wantDetail != "" && !strings.Contains(rec.Body.String(), tt.wantDetail) {
+				t.Errorf("body = %s, want detail %q", rec.Body.String(), tt.wantDetail)
+			}
+		})
+	}
+}
+
+func TestDecompressReusesReadersAfterInvalidStreams(t *testing.T) {
+	// Readers returned to the pool after failing on an invalid stream must still decompress the
+	// next valid one.
+	h := Decompress()(wrapFn(func(r *Request) Responder {
+		var body map[string]string
+		if problem := r.DecodeAndValidate(&body); problem != nil {
+			return problem
+		}
+		return Ok(body)
+	}))
+
+	for _, coding := range decompressEncodings {
+		compress := gzipped
+		if coding == "deflate" {
+			compress = deflated
+		}
+		for i := 0; i < 3; i++ {
+			for _, tc := range []struct {
+				body []byte
+				want int
+			}{
+				{body: []byte("not compressed"), want: http.StatusBadRequest},
+				{body: compress(t, `{"name":"Ann"}`), want: http.StatusOK},
+			} {
+				req := httptest.NewRequest(http.MethodPost, "/users", bytes.NewReader(tc.body))
+				req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+				req.Header.Set(HeaderContentEncoding, coding)
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, req)
+				if rec.Code != tc.want {
+					t.Fatalf("%s: status = %d, want %d: %s", coding, rec.Code, tc.want, rec.Body.String())
+				}
+			}
+		}
+	}
+}
@@ -57,6 +57,7 @@ func main() {
 		yuna.WithMetrics(),
 		yuna.WithPPROF(),
 		yuna.WithHealthChecks(),
+		yuna.WithPreStopDelay(time.Second*5),
 		yuna.WithAuthentication(authenticator))
 
 	app.RegisterHealthCheck(yuna.ComponentRegistration{
@@ -156,7 +157,10 @@ func main() {
 		})
 	})
 
-	app.Start()
+	// Run blocks until SIGINT/SIGTERM is received and then gracefully shuts down the servers
+	if err := app.Run(context.Background()); err != nil {
+		logger.Error("Application stopped with error", log.Error(err))
+	}
 }
 
 func sillyHandler(r *yuna.Request) yuna.Responder {
@@ -0,0 +1,282 @@
+package yuna
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// FileResponder is a Responder sending the contents of a file or blob using the semantics of
+// http.ServeContent. It supports conditional requests with If-Modified-Since, If-None-Match,
+// If-Unmodified-Since, and If-Match, as well as single and multipart byte ranges with If-Range.
+//
+// If the Content-Type isn't set with ContentType, it is derived from the extension of the file name
+// or sniffed from the first 512 bytes of the content.
+type FileResponder struct {
+	name        string
+	modtime     time.Time
+	content     io.ReadSeeker
+	closer      io.Closer
+	err         error
+	header      http.Header
+	disposition string
+	filename    string
+}
+
+// File returns a Responder sending the contents of the file, for example a file opened from an
+// embed.FS or os.DirFS. The name and modification time are taken from the file's fs.FileInfo. The
+// file is closed after responding.
+//
+// Files that don't implement io.Seeker, such as files in a zip archive, are still served, but
+// byte ranges can only be requested in ascending order.
+func File(f fs.File) *FileResponder {
+	return fileResponder(f, "")
+}
+
+// fileResponder returns a Responder sending the contents of the file. If name is empty the name of
+// the file is used.
+func fileResponder(f fs.File, name string) *FileResponder {
+	fr := newFileResponder(name, time.Time{}, nil)
+	fr.closer = f
+
+	info, err := f.Stat()
+	if err != nil {
+		fr.err = fmt.Errorf("stat file: %w", err)
+		return fr
+	}
+	if info.IsDir() {
+		fr.err = fmt.Errorf("%s is a directory", info.Name())
+		return fr
+	}
+	if fr.name == "" {
+		fr.name = info.Name()
+	}
+	fr.modtime = info.ModTime()
+
+	if rs, ok := f.(io.ReadSeeker); ok {
+		fr.content = rs
+	} else {
+		fr.content = &forwardSeeker{r: f, size: info.Size()}
+	}
+	return fr
+}
+
+// FileContent returns a Responder sending the content read from rs. The name is used to derive the
+// Content-Type and as the file name of an attachment. If modtime isn't the zero time, it is sent in
+// the Last-Modified header and used for conditional requests.
+func FileContent(name string, modtime time.Time, rs io.ReadSeeker) *FileResponder {
+	return newFileResponder(name, modtime, rs)
+}
+
+// FileBytes returns a Responder sending data as the content of a file. See FileContent.
+func FileBytes(name string, modtime time.Time, data []byte) *FileResponder {
+	return newFileResponder(name, modtime, bytes.NewReader(data))
+}
+
+func newFileResponder(name string, modtime time.Time, rs io.ReadSeeker) *FileResponder {
+	return &FileResponder{
+		name:    name,
+		modtime: modtime,
+		content: rs,
+		header:  http.Header{},
+	}
+}
+
+// ContentType sets the Content-Type of the response instead of deriving it from the name or
+// content.
+func (f *FileResponder) ContentType(contentType string) *FileResponder {
+	f.header.Set(HeaderContentType, contentType)
+	return f
+}
+
+// ETag sets the entity tag of the content, which is used to evaluate If-None-Match, If-Match, and
+// If-Range. The tag is quoted if it isn't already, prefix it with W/ for a weak entity tag.
+func (f *FileResponder) ETag(tag string) *FileResponder {
+	f.header.Set(HeaderETag, quoteETag(tag))
+	return f
+}
+
+// Header adds a header to the response.
+func (f *FileResponder) Header(key string, values ...string) *FileResponder {
+	for _, value := range values {
+		f.header.Add(key, value)
+	}
+	return f
+}
+
+// Attachment sends the content as an attachment, prompting the browser to download it rather than
+// display it. If filename is empty the name of the file is used.
+func (f *FileResponder) Attachment(filename string) *FileResponder {
+	f.disposition = "attachment"
+	f.filename = filename
+	return f
+}
+
+// Inline sends the content to be displayed by the browser. The filename is used if the user saves
+// the content, if empty the name of the file is used.
+func (f *FileResponder) Inline(filename string) *FileResponder {
+	f.disposition = "inline"
+	f.filename = filename
+	return f
+}
+
+func (f *FileResponder) Respond(w http.ResponseWriter, r *http.Request) error {
+	if f.closer != nil {
+		defer f.closer.Close()
+	}
+	if f.err != nil {
+		return InternalServerError(f.err).Respond(w, r)
+	}
+	if f.content == nil {
+		return InternalServerError(errors.New("file has no content")).Respond(w, r)
+	}
+
+	for key, values := range f.header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	if f.disposition != "" {
+		filename := f.filename
+		if filename == "" {
+			filename = path.Base(f.name)
+		}
+		w.Header().Set(HeaderContentDisposition, contentDisposition(f.disposition, filename))
+	}
+
+	http.ServeContent(w, r, f.name, f.modtime, f.content)
+	return nil
+}
+
+// contentDisposition formats a Content-Disposition header following RFC 6266. File names that
+// aren't plain ASCII are sent in the filename* parameter encoded as UTF-8, along with an ASCII
+// approximation in the filename parameter for clients that don't support it.
+func contentDisposition(disposition, filename string) string {
+	if filename == "" || filename == "." || filename == "/" {
+		return disposition
+	}
+
+	fallback := make([]byte, 0, len(filename))
+	plain := true
+	for i := 0; i < len(filename); i++ {
+		c := filename[i]
+		switch {
+		case c == '"' || c == '\\':
+			fallback = append(fallback, '\\', c)
+		case c < 0x20 || c == 0x7f:
+			fallback = append(fallback, '_')
+			plain = false
+		case c >= 0x80:
+			// Skip the remaining bytes of the UTF-8 sequence.
+			for i+1 < len(filename) && filename[i+1]&0xc0 == 0x80 {
+				i++
+			}
+			fallback = append(fallback, '_')
+			plain = false
+		default:
+			fallback = append(fallback, c)
+		}
+	}
+
+	value := disposition + `; filename="` + string(fallback) + `"`
+	if !plain {
+		value += "; filename*=UTF-8''" + encodeRFC5987(filename)
+	}
+	return value
+}
+
+// encodeRFC5987 percent-encodes the value for an extended header parameter as defined by RFC 8187.
+func encodeRFC5987(s string) string {
+	// url.PathEscape leaves a few characters unescaped that aren't allowed in attr-char.
+	escaped := url.PathEscape(s)
+	return strings.NewReplacer(
+		"'", "%27",
+		"(", "%28",
+		")", "%29",
+		"*", "%2A",
+		",", "%2C",
+		";", "%3B",
+		"=", "%3D",
+		"@", "%40",
+		":", "%3A",
+		"/", "%2F",
+	).Replace(escaped)
+}
+
+// sniffLen is the number of bytes http.ServeContent reads to sniff the Content-Type before seeking
+// back to the start.
+const sniffLen = 512
+
+// forwardSeeker adapts a reader that cannot seek into the io.ReadSeeker http.ServeContent requires.
+// Seeking to the end reports the size, seeking forward discards data, and seeking back is possible
+// as long as no more than the first 512 bytes have been read, which is enough for sniffing the
+// Content-Type.
+type forwardSeeker struct {
+	r    io.Reader
+	size int64
+	pos  int64  // position of the next Read
+	read int64  // bytes read from r
+	head []byte // the first bytes read from r, to allow seeking back for sniffing
+}
+
+func (s *forwardSeeker) Read(p []byte) (int, error) {
+	if s.pos >= s.size {
+		return 0, io.EOF
+	}
+
+	// Replay the bytes read while sniffing.
+	if s.pos < s.read {
+		n := copy(p, s.head[s.pos:s.read])
+		s.pos += int64(n)
+		return n, nil
+	}
+
+	// Discard the bytes skipped by seeking forward.
+	if s.pos > s.read {
+		skipped, err := io.CopyN(io.Discard, s.r, s.pos-s.read)
+		s.read += skipped
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := s.r.Read(p)
+	if s.read < sniffLen {
+		s.head = append(s.head, p[:min(int64(n), sniffLen-s.read)]...)
+	}
+	s.read += int64(n)
+	s.pos += int64(n)
+	return n, err
+}
+
+func (s *forwardSeeker) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = s.pos + offset
+	case io.SeekEnd:
+		pos = s.size + offset
+	default:
+		return 0, errors.New("seek: invalid whence")
+	}
+	if pos < 0 {
+		return 0, errors.New("seek: negative position")
+	}
+
+	// Seeking to the end is only used to determine the size and doesn't require reading. Seeking
+	// back is only possible while everything read so far is retained.
+	if pos < s.read && s.read > int64(len(s.head)) {
+		return 0, errors.New("seek: cannot seek backwards")
+	}
+	s.pos = pos
+	return pos, nil
+}
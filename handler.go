@@ -45,7 +45,18 @@ func wrap(h Handler, middlewares ...HttpMiddleware) http.Handler {
 		handler = middlewares[i](handler).ServeHTTP
 	}
 
-	return handler
+	return &endpoint{
+		Handler:     handler,
+		middlewares: middlewares,
+	}
+}
+
+// endpoint is the http.Handler registered with the router for a Handler. It retains the middleware
+// registered on the route, which are otherwise hidden from chi, so they can be reported by the route
+// inventory.
+type endpoint struct {
+	http.Handler
+	middlewares []HttpMiddleware
 }
 
 func wrapFn(fn HandlerFunc, middlewares ...HttpMiddleware) http.HandlerFunc {
@@ -44,6 +44,7 @@ const (
 	HeaderIfRange                         = "If-Range"
 	HeaderIfUnmodifiedSince               = "If-Unmodified-Since"
 	HeaderKeepAlive                       = "Keep-Alive"
+	HeaderLastEventID                     = "Last-Event-ID"
 	HeaderLastModified                    = "Last-Modified"
 	HeaderLink                            = "Link"
 	HeaderLocation                        = "Location"
@@ -54,6 +55,11 @@ const (
 	HeaderRange                           = "Range"
 	HeaderReferer                         = "Referer"
 	HeaderRetryAfter                      = "Retry-After"
+	HeaderSecWebSocketAccept              = "Sec-WebSocket-Accept"
+	HeaderSecWebSocketExtensions          = "Sec-WebSocket-Extensions"
+	HeaderSecWebSocketKey                 = "Sec-WebSocket-Key"
+	HeaderSecWebSocketProtocol            = "Sec-WebSocket-Protocol"
+	HeaderSecWebSocketVersion             = "Sec-WebSocket-Version"
 	HeaderServer                          = "Server"
 	HeaderSetCookie                       = "Set-Cookie"
 	HeaderStrictTransportSecurity         = "Strict-Transport-Security"
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -68,8 +69,9 @@ type Component struct {
 }
 
 type healthcheckHandler struct {
-	components []ComponentRegistration
-	router     chi.Router
+	components   []ComponentRegistration
+	router       chi.Router
+	shuttingDown atomic.Bool
 }
 
 func newHealthcheckHandler() *healthcheckHandler {
@@ -91,6 +93,12 @@ func (h *healthcheckHandler) register(c ComponentRegistration) {
 	h.components = append(h.components, c)
 }
 
+// markShuttingDown forces the readiness endpoint to report DOWN regardless of the status of the
+// registered components so that traffic is no longer routed to the instance.
+func (h *healthcheckHandler) markShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
 func (h *healthcheckHandler) live(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
 	w.Header().Set("Pragma", "no-cache")
@@ -101,7 +109,18 @@ func (h *healthcheckHandler) live(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *healthcheckHandler) ready(w http.ResponseWriter, r *http.Request) {
-	resp := readyStatus(r.Context(), h.components)
+	var resp HealthResponse
+	if h.shuttingDown.Load() {
+		// Once the application is shutting down the components are no longer checked. The instance
+		// should be taken out of rotation regardless of the state of its dependencies.
+		resp = HealthResponse{
+			Status:     StatusDown,
+			Components: make([]Component, 0),
+			Timestamp:  time.Now(),
+		}
+	} else {
+		resp = readyStatus(r.Context(), h.components)
+	}
 
 	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
 	w.Header().Set("Pragma", "no-cache")
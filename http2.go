@@ -0,0 +1,32 @@
+package yuna
+
+import (
+	"net/http"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// configureHTTP2 applies the HTTP/2 settings to the main HTTP server.
+//
+// When h2c is enabled, the server accepts unencrypted HTTP/2 connections with prior knowledge, which
+// is handled natively by net/http, and HTTP/1.1 connections upgraded to HTTP/2 using the Upgrade
+// header, which net/http doesn't support and is handled by wrapping the server's Handler. Because the
+// wrapping occurs outside the router, all of Yuna's middleware still applies to HTTP/2 requests.
+func configureHTTP2(server *http.Server, conf *config) {
+	server.HTTP2 = conf.http2
+
+	if !conf.h2c {
+		return
+	}
+
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	server.Protocols = protocols
+
+	// The http2.Server picks up the settings from server.HTTP2 as h2c passes the http.Server handling
+	// the upgrade request as the base configuration.
+	server.Handler = h2c.NewHandler(server.Handler, &http2.Server{})
+}
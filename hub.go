@@ -0,0 +1,293 @@
+package yuna
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/jkratz55/yuna/internal"
+)
+
+// A DropPolicy determines what a Hub does when a subscriber's buffer is full because it isn't
+// receiving events as fast as they are published.
+type DropPolicy int
+
+const (
+	// DropNewest discards the event being published for the slow subscriber.
+	DropNewest DropPolicy = iota
+
+	// DropOldest discards the oldest event buffered for the slow subscriber to make room for the
+	// event being published.
+	DropOldest
+
+	// DropSubscriber unsubscribes the slow subscriber, closing its channel, which ends its
+	// Server-Sent Events stream. The client reconnects and resumes from the last event it received
+	// if the Hub retains enough history.
+	DropSubscriber
+)
+
+// A HubOption configures a Hub created with NewHub.
+type HubOption func(h *Hub)
+
+// HubBufferSize sets the number of events buffered for each subscriber. The default is 16.
+func HubBufferSize(n int) HubOption {
+	if n < 1 {
+		panic("hub buffer size must be at least 1")
+	}
+	return func(h *Hub) {
+		h.bufferSize = n
+	}
+}
+
+// HubDropPolicy sets what happens when a subscriber's buffer is full. The default is DropNewest.
+func HubDropPolicy(policy DropPolicy) HubOption {
+	return func(h *Hub) {
+		h.dropPolicy = policy
+	}
+}
+
+// HubHistory sets the number of events retained for each topic to resume streams from the
+// Last-Event-ID sent by reconnecting clients. The default is zero, which disables resumption.
+func HubHistory(n int) HubOption {
+	return func(h *Hub) {
+		h.historySize = max(n, 0)
+	}
+}
+
+// HubMeterProvider sets the MeterProvider used to record the number of subscribers and dropped
+// events. The default is the global MeterProvider.
+func HubMeterProvider(mp metric.MeterProvider) HubOption {
+	return func(h *Hub) {
+		h.meterProvider = mp
+	}
+}
+
+// A Hub broadcasts events published to a topic to every subscriber of the topic, within the
+// process. It is intended to fan out events to Server-Sent Events streams:
+//
+//	hub := yuna.NewHub(yuna.HubHistory(100))
+//
+//	router.Get("/orders/events", func(r *yuna.Request) yuna.Responder {
+//		return yuna.SSE(hub.Subscribe(r.Context(), r.LastEventID(), "orders"))
+//	})
+//
+//	hub.Publish("orders", yuna.Event{Event: "created", Data: order})
+//
+// Publish never blocks on subscribers. Each subscriber has a buffer of events, and when a
+// subscriber falls behind and its buffer is full the DropPolicy of the Hub applies. A Hub is safe
+// for concurrent use by multiple goroutines.
+type Hub struct {
+	bufferSize    int
+	dropPolicy    DropPolicy
+	historySize   int
+	meterProvider metric.MeterProvider
+
+	mu          sync.Mutex
+	seq         uint64
+	subscribers map[string]map[*subscriber]struct{}
+	history     map[string][]hubEvent
+
+	subscriberCount metric.Int64UpDownCounter
+	droppedEvents   metric.Int64Counter
+}
+
+type subscriber struct {
+	events chan Event
+	stop   func() bool
+	topics []string
+	closed bool
+}
+
+// hubEvent is an event retained in the history of a topic along with the order it was published.
+type hubEvent struct {
+	seq   uint64
+	event Event
+}
+
+// NewHub creates a Hub.
+func NewHub(opts ...HubOption) *Hub {
+	h := &Hub{
+		bufferSize:    16,
+		dropPolicy:    DropNewest,
+		historySize:   0,
+		meterProvider: otel.GetMeterProvider(),
+		subscribers:   make(map[string]map[*subscriber]struct{}),
+		history:       make(map[string][]hubEvent),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	meter := h.meterProvider.Meter(internal.Scope, metric.WithInstrumentationVersion(internal.Version))
+	var err error
+	h.subscriberCount, err = meter.Int64UpDownCounter("sse.hub.subscribers",
+		metric.WithDescription("Number of subscribers to Hub topics"))
+	if err != nil {
+		panic(err)
+	}
+	h.droppedEvents, err = meter.Int64Counter("sse.hub.events.dropped",
+		metric.WithDescription("Number of events dropped because a subscriber was too slow"))
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+// Publish sends the event to all subscribers of the topic. If the event doesn't have an ID, one is
+// assigned so clients can resume the stream after it.
+func (h *Hub) Publish(topic string, ev Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq++
+	if ev.ID == "" {
+		ev.ID = strconv.FormatUint(h.seq, 10)
+	}
+
+	if h.historySize > 0 {
+		history := append(h.history[topic], hubEvent{seq: h.seq, event: ev})
+		if len(history) > h.historySize {
+			history = slices.Delete(history, 0, len(history)-h.historySize)
+		}
+		h.history[topic] = history
+	}
+
+	for sub := range h.subscribers[topic] {
+		h.send(sub, ev)
+	}
+}
+
+// send delivers the event to the subscriber without blocking, applying the DropPolicy if the
+// subscriber's buffer is full. The caller must hold h.mu.
+func (h *Hub) send(sub *subscriber, ev Event) {
+	select {
+	case sub.events <- ev:
+		return
+	default:
+	}
+
+	h.droppedEvents.Add(context.Background(), 1)
+	switch h.dropPolicy {
+	case DropOldest:
+		// The subscriber may have received an event in the meantime, in which case nothing needs
+		// to be discarded.
+		select {
+		case <-sub.events:
+		default:
+		}
+		select {
+		case sub.events <- ev:
+		default:
+		}
+	case DropSubscriber:
+		h.unsubscribe(sub)
+	}
+}
+
+// Subscribe subscribes to events published to the topics, returning a channel receiving the events.
+// The subscription ends, and the channel is closed, when ctx is done, or when the subscriber is
+// dropped for being too slow under the DropSubscriber policy.
+//
+// If lastEventID is the ID of an event retained in the history of the topics, the events published
+// after it are received first, so a stream can resume where the client left off. Otherwise, only
+// events published after subscribing are received.
+func (h *Hub) Subscribe(ctx context.Context, lastEventID string, topics ...string) <-chan Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	replay := h.replay(lastEventID, topics)
+	sub := &subscriber{
+		events: make(chan Event, max(h.bufferSize, len(replay))),
+		topics: topics,
+	}
+	for _, ev := range replay {
+		sub.events <- ev
+	}
+
+	if ctx.Err() != nil {
+		close(sub.events)
+		return sub.events
+	}
+
+	for _, topic := range topics {
+		subs, ok := h.subscribers[topic]
+		if !ok {
+			subs = make(map[*subscriber]struct{})
+			h.subscribers[topic] = subs
+		}
+		subs[sub] = struct{}{}
+	}
+	h.subscriberCount.Add(context.Background(), 1)
+
+	sub.stop = context.AfterFunc(ctx, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.unsubscribe(sub)
+	})
+	return sub.events
+}
+
+// replay returns the events retained in the history of the topics published after the event with
+// the ID, in the order they were published. The caller must hold h.mu.
+func (h *Hub) replay(lastEventID string, topics []string) []Event {
+	if lastEventID == "" || h.historySize == 0 {
+		return nil
+	}
+
+	var lastSeq uint64
+	found := false
+	for _, topic := range topics {
+		for _, he := range h.history[topic] {
+			if he.event.ID == lastEventID {
+				lastSeq, found = he.seq, true
+			}
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	missed := make([]hubEvent, 0)
+	for _, topic := range slices.Compact(slices.Sorted(slices.Values(topics))) {
+		for _, he := range h.history[topic] {
+			if he.seq > lastSeq {
+				missed = append(missed, he)
+			}
+		}
+	}
+	slices.SortFunc(missed, func(a, b hubEvent) int {
+		return cmp.Compare(a.seq, b.seq)
+	})
+
+	events := make([]Event, 0, len(missed))
+	for _, he := range missed {
+		events = append(events, he.event)
+	}
+	return events
+}
+
+// unsubscribe removes the subscriber from its topics and closes its channel. The caller must hold
+// h.mu.
+func (h *Hub) unsubscribe(sub *subscriber) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+
+	for _, topic := range sub.topics {
+		subs := h.subscribers[topic]
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(h.subscribers, topic)
+		}
+	}
+
+	sub.stop()
+	close(sub.events)
+	h.subscriberCount.Add(context.Background(), -1)
+}
@@ -6,4 +6,6 @@ const (
 	ContextKeyLogger ContextKey = iota
 	ContextKeyRestyTemplatedPath
 	ContextKeyPrincipal
+	ContextKeySettings
+	ContextKeyProduces
 )
@@ -14,6 +14,18 @@ type ResponseWriter struct {
 	statusCode  int
 	bytesWrote  uint64
 	wroteHeader atomic.Uint32
+
+	// compressionSavings is the difference between the size of the response body before and after
+	// it was compressed by a handler further down the chain, and compressed reports whether the
+	// handler compressed the response. The uncompressed size is only recorded for compressed
+	// responses, see BytesWroteUncompressed.
+	compressionSavings int64
+	compressed         bool
+
+	// requestCompressed and requestDecompressed are the sizes of the request body before and after
+	// it was decompressed by a handler further down the chain.
+	requestCompressed   uint64
+	requestDecompressed uint64
 }
 
 func newResponseWriter(w http.ResponseWriter) *ResponseWriter {
@@ -89,15 +101,27 @@ func (r *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	if !ok {
 		return nil, nil, http.ErrNotSupported
 	}
-	return hijacker.Hijack()
+	conn, brw, err := hijacker.Hijack()
+	if err == nil && r.wroteHeader.CompareAndSwap(0, 1) {
+		// The response to a hijacked connection is written directly to the connection, typically to
+		// switch to another protocol such as WebSocket.
+		r.statusCode = http.StatusSwitchingProtocols
+	}
+	return conn, brw, err
 }
 
 func (r *ResponseWriter) Flush() {
-	flusher, ok := r.ResponseWriter.(http.Flusher)
-	if !ok {
-		return
+	_ = r.FlushError()
+}
+
+// FlushError flushes buffered data to the client, returning http.ErrNotSupported if the underlying
+// ResponseWriter cannot be flushed. It is used by http.ResponseController.
+func (r *ResponseWriter) FlushError() error {
+	// Flushing implicitly writes the header with status 200 OK if it wasn't written yet.
+	if r.wroteHeader.CompareAndSwap(0, 1) {
+		r.statusCode = http.StatusOK
 	}
-	flusher.Flush()
+	return http.NewResponseController(r.ResponseWriter).Flush()
 }
 
 func (r *ResponseWriter) Status() int {
@@ -108,6 +132,38 @@ func (r *ResponseWriter) BytesWrote() uint64 {
 	return r.bytesWrote
 }
 
+// BytesWroteUncompressed returns the size of the response body before it was compressed, and false
+// if no handler recorded compressing it with RecordCompression.
+func (r *ResponseWriter) BytesWroteUncompressed() (uint64, bool) {
+	if !r.compressed {
+		return 0, false
+	}
+	return uint64(int64(r.bytesWrote) + r.compressionSavings), true
+}
+
+// RecordCompression records that uncompressed bytes of the response body were compressed to
+// compressed bytes before being written to the ResponseWriter.
+func (r *ResponseWriter) RecordCompression(uncompressed, compressed uint64) {
+	r.compressionSavings += int64(uncompressed) - int64(compressed)
+	r.compressed = true
+}
+
+// RecordDecompression records that compressed bytes of the request body were decompressed to
+// decompressed bytes before being read by the handler.
+func (r *ResponseWriter) RecordDecompression(compressed, decompressed uint64) {
+	r.requestCompressed += compressed
+	r.requestDecompressed += decompressed
+}
+
+// RequestCompressionRatio returns the ratio of the decompressed size to the compressed size of the
+// request body, and false if the request body wasn't decompressed.
+func (r *ResponseWriter) RequestCompressionRatio() (float64, bool) {
+	if r.requestCompressed == 0 {
+		return 0, false
+	}
+	return float64(r.requestDecompressed) / float64(r.requestCompressed), true
+}
+
 func (r *ResponseWriter) WroteHeader() bool {
 	return r.wroteHeader.Load() == 1
 }
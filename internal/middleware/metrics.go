@@ -29,6 +29,30 @@ func InstrumentHandler(meterProvider metric.MeterProvider, buckets []float64) fu
 		panic(err)
 	}
 
+	responseSize, err := meter.Int64Histogram("http.server.response.body.size",
+		metric.WithDescription("Size in bytes of response bodies"),
+		metric.WithUnit("By"),
+		metric.WithExplicitBucketBoundaries(100, 1<<10, 10<<10, 100<<10, 1<<20, 10<<20, 100<<20, 1<<30))
+	if err != nil {
+		panic(err)
+	}
+
+	uncompressedResponseSize, err := meter.Int64Histogram("http.server.response.body.uncompressed_size",
+		metric.WithDescription("Size in bytes of compressed response bodies before compression"),
+		metric.WithUnit("By"),
+		metric.WithExplicitBucketBoundaries(100, 1<<10, 10<<10, 100<<10, 1<<20, 10<<20, 100<<20, 1<<30))
+	if err != nil {
+		panic(err)
+	}
+
+	requestCompressionRatio, err := meter.Float64Histogram("http.server.request.body.compression_ratio",
+		metric.WithDescription("Ratio of the decompressed size to the compressed size of compressed request bodies"),
+		metric.WithUnit("1"),
+		metric.WithExplicitBucketBoundaries(1, 2, 5, 10, 20, 50, 100, 200, 500, 1000))
+	if err != nil {
+		panic(err)
+	}
+
 	inFlightRequests, err := meter.Int64UpDownCounter("http.server.requests.in_flight",
 		metric.WithDescription("Number of in-flight requests"))
 	if err != nil {
@@ -55,12 +79,19 @@ func InstrumentHandler(meterProvider metric.MeterProvider, buckets []float64) fu
 				path = chiCtx.RoutePattern()
 			}
 
-			requestLatency.Record(r.Context(), dur.Seconds(),
-				metric.WithAttributes(
-					attribute.Int("http.response.status_code", rw.statusCode),
-					attribute.String("http.request.method", r.Method),
-					attribute.String("http.route", path),
-				))
+			attrs := metric.WithAttributes(
+				attribute.Int("http.response.status_code", rw.statusCode),
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", path),
+			)
+			requestLatency.Record(r.Context(), dur.Seconds(), attrs)
+			responseSize.Record(r.Context(), int64(rw.BytesWrote()), attrs)
+			if size, ok := rw.BytesWroteUncompressed(); ok {
+				uncompressedResponseSize.Record(r.Context(), int64(size), attrs)
+			}
+			if ratio, ok := rw.RequestCompressionRatio(); ok {
+				requestCompressionRatio.Record(r.Context(), ratio, attrs)
+			}
 		})
 	}
 }
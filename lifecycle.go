@@ -0,0 +1,276 @@
+package yuna
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jkratz55/yuna/log"
+)
+
+// A LifecycleHook is a function invoked when the application starts or stops. The context passed to
+// the hook is cancelled when the hook exceeds its timeout.
+type LifecycleHook func(ctx context.Context) error
+
+// A HookOption configures a LifecycleHook registered with OnStart or OnStop.
+type HookOption func(opts *hookOptions)
+
+type hookOptions struct {
+	timeout   time.Duration
+	dependsOn []string
+}
+
+// HookTimeout sets the maximum duration the hook is allowed to run, which must be greater than zero.
+// If not set, the timeout set by WithDefaultHookTimeout is used.
+func HookTimeout(timeout time.Duration) HookOption {
+	if timeout <= 0 {
+		panic("hook timeout must be greater than zero")
+	}
+	return func(opts *hookOptions) {
+		opts.timeout = timeout
+	}
+}
+
+// DependsOn declares that the component depends on the named components. Start hooks of the
+// dependencies run before the component's start hook, and stop hooks of the dependencies run after
+// the component's stop hook.
+func DependsOn(names ...string) HookOption {
+	return func(opts *hookOptions) {
+		opts.dependsOn = append(opts.dependsOn, names...)
+	}
+}
+
+// OnStart registers a hook that runs when the application is started, before the main HTTP server
+// and operations HTTP server begin accepting connections. This is the place to open database pools,
+// start Kafka consumers, background workers, etc.
+//
+// Start hooks run sequentially in the order they were registered, unless DependsOn is used to
+// declare dependencies between components, in which case dependencies are always started first. If a
+// start hook returns an error, the remaining start hooks are not run, the stop hooks of the components
+// already started are run, and the server does not start.
+func (z *Yuna) OnStart(name string, fn LifecycleHook, opts ...HookOption) {
+	z.lifecycle.register(name, fn, nil, opts...)
+}
+
+// OnStop registers a hook that runs when the application is shutting down, after the main HTTP server
+// has stopped accepting connections and in-flight requests have completed. This is the place to
+// close database pools, stop Kafka consumers, background workers, etc.
+//
+// Stop hooks run sequentially in the reverse order of the start hooks, so a component is stopped
+// before the components it depends on. A component registered with the same name as a start hook is
+// only stopped if it was started successfully. Errors returned by stop hooks don't prevent the other
+// stop hooks from running, and are combined in the error returned by Shutdown or Run.
+func (z *Yuna) OnStop(name string, fn LifecycleHook, opts ...HookOption) {
+	z.lifecycle.register(name, nil, fn, opts...)
+}
+
+type component struct {
+	name         string
+	start        LifecycleHook
+	stop         LifecycleHook
+	startTimeout time.Duration
+	stopTimeout  time.Duration
+	dependsOn    []string
+	started      bool
+}
+
+// lifecycle tracks the components registered with OnStart and OnStop and runs their hooks in
+// dependency order.
+type lifecycle struct {
+	mu             sync.Mutex
+	components     []*component
+	index          map[string]*component
+	order          []*component
+	stopped        bool
+	defaultTimeout time.Duration
+	logger         *log.Logger
+}
+
+func newLifecycle(defaultTimeout time.Duration, logger *log.Logger) *lifecycle {
+	return &lifecycle{
+		components:     make([]*component, 0),
+		index:          make(map[string]*component),
+		defaultTimeout: defaultTimeout,
+		logger:         logger,
+	}
+}
+
+func (l *lifecycle) register(name string, start, stop LifecycleHook, opts ...HookOption) {
+	options := &hookOptions{
+		timeout: l.defaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.order != nil {
+		l.logger.Warn(fmt.Sprintf("Lifecycle hook for %s registered after the application started. The hook will not run.", name))
+		return
+	}
+
+	c, ok := l.index[name]
+	if !ok {
+		c = &component{name: name}
+		l.index[name] = c
+		l.components = append(l.components, c)
+	}
+
+	if start != nil {
+		if c.start != nil {
+			l.logger.Warn(fmt.Sprintf("Start hook for %s was already registered and has been replaced", name))
+		}
+		c.start = start
+		c.startTimeout = options.timeout
+	}
+	if stop != nil {
+		if c.stop != nil {
+			l.logger.Warn(fmt.Sprintf("Stop hook for %s was already registered and has been replaced", name))
+		}
+		c.stop = stop
+		c.stopTimeout = options.timeout
+	}
+	c.dependsOn = append(c.dependsOn, options.dependsOn...)
+}
+
+// start runs the start hooks in dependency order. If a start hook fails, the components already
+// started are stopped and the errors are returned. Once the lifecycle was stopped it cannot be
+// started again, since the stop hooks would never run a second time.
+func (l *lifecycle) start() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.stopped {
+		return errors.New("lifecycle: cannot start after the application was stopped")
+	}
+
+	order, err := l.sort()
+	if err != nil {
+		return fmt.Errorf("lifecycle: %w", err)
+	}
+	l.order = order
+
+	for _, c := range order {
+		if c.start != nil {
+			if err := l.runHook(c.name, "start", c.start, c.startTimeout); err != nil {
+				return errors.Join(err, l.stopLocked())
+			}
+		}
+		c.started = true
+	}
+	return nil
+}
+
+// stop runs the stop hooks of the started components in reverse dependency order. Stop hooks only
+// ever run once, subsequent calls are a no-op.
+func (l *lifecycle) stop() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stopLocked()
+}
+
+func (l *lifecycle) stopLocked() error {
+	if l.stopped {
+		return nil
+	}
+	l.stopped = true
+
+	var errs []error
+	for _, c := range slices.Backward(l.order) {
+		if !c.started || c.stop == nil {
+			continue
+		}
+		if err := l.runHook(c.name, "stop", c.stop, c.stopTimeout); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (l *lifecycle) runHook(name, phase string, fn LifecycleHook, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	logger := l.logger.With(log.String("component", name), log.String("phase", phase))
+	logger.Debug(fmt.Sprintf("Running %s hook for %s", phase, name))
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- fn(ctx)
+	}()
+
+	// The hook is run in a separate goroutine so that a hook that doesn't respect the context
+	// cancellation cannot block the application from starting or stopping indefinitely.
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %s", timeout)
+	}
+
+	if err != nil {
+		logger.Error(fmt.Sprintf("The %s hook for %s failed", phase, name),
+			log.Error(err),
+			log.Duration("duration", time.Since(start)))
+		return fmt.Errorf("%s hook %s: %w", phase, name, err)
+	}
+
+	logger.Info(fmt.Sprintf("The %s hook for %s completed", phase, name),
+		log.Duration("duration", time.Since(start)))
+	return nil
+}
+
+// sort returns the components ordered such that every component comes after its dependencies.
+// Components without a dependency relationship keep the order they were registered in.
+func (l *lifecycle) sort() ([]*component, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[string]int, len(l.components))
+	order := make([]*component, 0, len(l.components))
+
+	var visit func(c *component, path []string) error
+	visit = func(c *component, path []string) error {
+		switch state[c.name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle detected: %v", append(path, c.name))
+		}
+
+		state[c.name] = visiting
+		for _, dep := range c.dependsOn {
+			d, ok := l.index[dep]
+			if !ok {
+				return fmt.Errorf("component %s depends on unknown component %s", c.name, dep)
+			}
+			if err := visit(d, append(path, c.name)); err != nil {
+				return err
+			}
+		}
+		state[c.name] = visited
+		order = append(order, c)
+		return nil
+	}
+
+	for _, c := range l.components {
+		if err := visit(c, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,60 @@
+package yuna
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLifecycleHookTimeout(t *testing.T) {
+	app := New(WithDefaultHookTimeout(50 * time.Millisecond))
+
+	release := make(chan struct{})
+	defer close(release)
+	// The hook ignores its context, so only the timeout unblocks the application.
+	app.OnStart("stuck", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	app.OnStart("fast", func(ctx context.Context) error {
+		return nil
+	}, HookTimeout(time.Second))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- app.lifecycle.start()
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "start hook stuck: timed out after 50ms") {
+			t.Errorf("start() error = %v, want timeout of the stuck hook", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("start() blocked on a hook ignoring its context")
+	}
+}
+
+func TestHookTimeoutMustBePositive(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func()
+	}{
+		{name: "default zero", fn: func() { WithDefaultHookTimeout(0) }},
+		{name: "default negative", fn: func() { WithDefaultHookTimeout(-time.Second) }},
+		{name: "hook zero", fn: func() { HookTimeout(0) }},
+		{name: "hook negative", fn: func() { HookTimeout(-time.Second) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("no panic for a non-positive timeout")
+				}
+			}()
+			tt.fn()
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package yuna
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// MaxBodySize returns an HTTP middleware that limits the size of request bodies to n bytes using
+// http.MaxBytesReader.
+//
+// Requests declaring a Content-Length larger than n are rejected with a 413 Content Too Large
+// problem before the handler is called. Otherwise, reading more than n bytes from the body returns
+// an *http.MaxBytesError, which Request.DecodeAndValidate and Typed handlers convert to a 413
+// Content Too Large problem.
+//
+// MaxBodySize replaces the limit set by WithMaxRequestBodySize, so a route can accept larger or
+// smaller bodies than the rest of the application. A limit of zero or less removes the limit.
+func MaxBodySize(n int64) HttpMiddleware {
+	return limitBody(n, true)
+}
+
+// limitBody returns an HTTP middleware limiting the size of request bodies to n bytes. If eager is
+// true, requests declaring a larger Content-Length are rejected immediately. The limit set by
+// WithMaxRequestBodySize isn't eager, since it can be raised by MaxBodySize on a route.
+func limitBody(n int64, eager bool) HttpMiddleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Remove the limit set earlier in the chain so n can be larger than that limit.
+			body := r.Body
+			if limited, ok := body.(*maxBytesBody); ok {
+				body = limited.body
+			}
+
+			if n <= 0 {
+				r.Body = body
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if eager && r.ContentLength > n {
+				ContentTooLarge().
+					SetDetail(fmt.Sprintf("The request content exceeds the limit of %d bytes.", n)).
+					ServeHTTP(w, r)
+				return
+			}
+
+			r.Body = &maxBytesBody{
+				ReadCloser: http.MaxBytesReader(w, body, n),
+				body:       body,
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// maxBytesBody is a request body limited by MaxBodySize. It keeps a reference to the original body
+// so the limit can be replaced further down the middleware chain.
+type maxBytesBody struct {
+	io.ReadCloser
+	body io.ReadCloser
+}
@@ -0,0 +1,177 @@
+package yuna
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// systemdListenFdsStart is the first file descriptor passed by systemd socket activation. File
+	// descriptors 0, 1, and 2 are reserved for stdin, stdout, and stderr.
+	systemdListenFdsStart = 3
+
+	systemdNameHTTP       = "http"
+	systemdNameOperations = "operations"
+)
+
+// listen creates the listeners for the main HTTP server and the operations HTTP server based on
+// the configuration.
+func (z *Yuna) listen() (appLn net.Listener, opsLn net.Listener, err error) {
+	if z.config.systemdSocketActivation {
+		appLn, opsLn, err = systemdListeners()
+		if err != nil {
+			return nil, nil, fmt.Errorf("systemd socket activation: %w", err)
+		}
+		if appLn == nil {
+			z.logger.Info("Process was not socket activated by systemd, listening on configured addresses")
+		}
+	}
+
+	if appLn == nil {
+		appLn, err = z.listenApp()
+		if err != nil {
+			if opsLn != nil {
+				_ = opsLn.Close()
+			}
+			return nil, nil, fmt.Errorf("main HTTP server: %w", err)
+		}
+	}
+
+	if opsLn == nil {
+		opsLn, err = z.listenOps()
+		if err != nil {
+			_ = appLn.Close()
+			return nil, nil, fmt.Errorf("operations HTTP server: %w", err)
+		}
+	}
+
+	return appLn, opsLn, nil
+}
+
+func (z *Yuna) listenApp() (net.Listener, error) {
+	if z.config.unixSocketPath != "" {
+		return listenUnix(z.config.unixSocketPath, z.config.unixSocketMode)
+	}
+
+	addr := z.config.listenAddress
+	if addr == "" {
+		addr = fmt.Sprintf(":%d", z.config.httpPort)
+	}
+	return net.Listen("tcp", addr)
+}
+
+func (z *Yuna) listenOps() (net.Listener, error) {
+	addr := z.config.operationListenAddr
+	if addr == "" {
+		addr = fmt.Sprintf(":%d", z.config.operationHTTPPort)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// listenUnix listens on a Unix domain socket at the given path. If a socket file already exists at
+// the path, likely left behind by a previous process that didn't exit cleanly, it is removed first.
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if info, err := os.Stat(path); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("%s exists and is not a unix socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("remove stale unix socket: %w", err)
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode != 0 {
+		if err := os.Chmod(path, mode); err != nil {
+			_ = ln.Close()
+			return nil, fmt.Errorf("chmod unix socket: %w", err)
+		}
+	}
+	return ln, nil
+}
+
+// systemdListeners returns the listeners passed to the process through systemd socket activation.
+// If the process was not socket activated, nil listeners and a nil error are returned.
+//
+// See https://www.freedesktop.org/software/systemd/man/latest/sd_listen_fds.html
+func systemdListeners() (appLn net.Listener, opsLn net.Listener, err error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil, nil
+	}
+	nfds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || nfds <= 0 {
+		return nil, nil, nil
+	}
+
+	var names []string
+	if v := os.Getenv("LISTEN_FDNAMES"); v != "" {
+		names = strings.Split(v, ":")
+	}
+
+	// The environment variables are unset so that they aren't inherited by child processes, which
+	// would otherwise believe the sockets were passed to them.
+	_ = os.Unsetenv("LISTEN_PID")
+	_ = os.Unsetenv("LISTEN_FDS")
+	_ = os.Unsetenv("LISTEN_FDNAMES")
+
+	listeners := make([]net.Listener, 0, nfds)
+	closeAll := func() {
+		for _, ln := range listeners {
+			_ = ln.Close()
+		}
+	}
+
+	for i := 0; i < nfds; i++ {
+		name := "LISTEN_FD_" + strconv.Itoa(systemdListenFdsStart+i)
+		if i < len(names) {
+			name = names[i]
+		}
+
+		f := os.NewFile(uintptr(systemdListenFdsStart+i), name)
+		ln, err := net.FileListener(f)
+		_ = f.Close() // FileListener duplicates the file descriptor
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("file descriptor %d: %w", systemdListenFdsStart+i, err)
+		}
+		listeners = append(listeners, ln)
+
+		switch name {
+		case systemdNameHTTP:
+			appLn = ln
+		case systemdNameOperations:
+			opsLn = ln
+		}
+	}
+
+	// If the sockets were not explicitly named, they are assigned in the order they were passed.
+	if appLn == nil && opsLn == nil {
+		appLn = listeners[0]
+		if len(listeners) > 1 {
+			opsLn = listeners[1]
+		}
+	}
+	if appLn == nil {
+		closeAll()
+		return nil, nil, errors.New("no socket named \"http\" was passed")
+	}
+
+	// Any additional sockets passed are not used by Yuna.
+	for _, ln := range listeners {
+		if ln != appLn && ln != opsLn {
+			_ = ln.Close()
+		}
+	}
+
+	return appLn, opsLn, nil
+}
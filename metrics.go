@@ -0,0 +1,66 @@
+package yuna
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/jkratz55/yuna/internal"
+)
+
+// responderMetrics are the instruments recorded by responders that stream to the client, which
+// aren't covered by the request duration recorded when the handler returns.
+type responderMetrics struct {
+	sseStreams metric.Int64UpDownCounter
+	sseEvents  metric.Int64Counter
+	wsConns    metric.Int64UpDownCounter
+	wsMessages metric.Int64Counter
+}
+
+func newResponderMetrics(mp metric.MeterProvider) *responderMetrics {
+	if mp == nil {
+		mp = otel.GetMeterProvider()
+	}
+	meter := mp.Meter(internal.Scope, metric.WithInstrumentationVersion(internal.Version))
+
+	sseStreams, err := meter.Int64UpDownCounter("http.server.sse.streams",
+		metric.WithDescription("Number of connected Server-Sent Events streams"))
+	if err != nil {
+		panic(err)
+	}
+	sseEvents, err := meter.Int64Counter("http.server.sse.events",
+		metric.WithDescription("Number of Server-Sent Events sent to clients"))
+	if err != nil {
+		panic(err)
+	}
+	wsConns, err := meter.Int64UpDownCounter("http.server.websocket.connections",
+		metric.WithDescription("Number of open WebSocket connections"))
+	if err != nil {
+		panic(err)
+	}
+	wsMessages, err := meter.Int64Counter("http.server.websocket.messages",
+		metric.WithDescription("Number of WebSocket messages received from and sent to clients"))
+	if err != nil {
+		panic(err)
+	}
+
+	return &responderMetrics{
+		sseStreams: sseStreams,
+		sseEvents:  sseEvents,
+		wsConns:    wsConns,
+		wsMessages: wsMessages,
+	}
+}
+
+// routeAttr returns the http.route attribute for the request. The route pattern is used rather than
+// the path to keep the cardinality of the metrics bounded.
+func routeAttr(r *http.Request) attribute.KeyValue {
+	route := "undefined"
+	if chiCtx := chi.RouteContext(r.Context()); chiCtx != nil && chiCtx.RoutePattern() != "" {
+		route = chiCtx.RoutePattern()
+	}
+	return attribute.String("http.route", route)
+}
@@ -13,6 +13,7 @@ const (
 const (
 	MIMEApplicationJSON                  = "application/json"
 	MIMEApplicationJSONCharsetUTF8       = MIMEApplicationJSON + "; " + CharsetUTF8
+	MIMEApplicationProblemJSON           = "application/problem+json"
 	MIMEApplicationJavascript            = "application/javascript"
 	MIMEApplicationJavascriptCharsetUTF8 = MIMEApplicationJavascript + "; " + CharsetUTF8
 	MIMEApplicationXML                   = "application/xml"
@@ -20,12 +21,15 @@ const (
 	MIMEApplicationForm                  = "application/x-www-form-urlencoded"
 	MIMEApplicationProtobuf              = "application/protobuf"
 	MIMEApplicationMsgpack               = "application/msgpack"
+	MIMEApplicationNDJSON                = "application/x-ndjson"
 	MIMETextXML                          = "text/xml"
 	MIMETextXMLCharsetUTF8               = MIMETextXML + "; " + CharsetUTF8
 	MIMETextHTML                         = "text/html"
 	MIMETextHTMLCharsetUTF8              = MIMETextHTML + "; " + CharsetUTF8
 	MIMETextPlain                        = "text/plain"
 	MIMETextPlainCharsetUTF8             = MIMETextPlain + "; " + CharsetUTF8
+	MIMETextEventStream                  = "text/event-stream"
+	MIMETextCSV                          = "text/csv"
 	MIMEMultipartForm                    = "multipart/form-data"
 	MIMEOctetStream                      = "application/octet-stream"
 )
@@ -0,0 +1,190 @@
+package yuna
+
+import (
+	"crypto/x509"
+	"net/http"
+	"strings"
+)
+
+// A CertificateField identifies a field of an X.509 client certificate.
+type CertificateField string
+
+const (
+	// CertFieldCommonName is the Common Name (CN) of the certificate subject.
+	CertFieldCommonName CertificateField = "CN"
+	// CertFieldURI is a URI Subject Alternative Name, such as a SPIFFE ID.
+	CertFieldURI CertificateField = "URI"
+	// CertFieldDNSName is a DNS Subject Alternative Name.
+	CertFieldDNSName CertificateField = "DNS"
+	// CertFieldOrganization is an Organization (O) of the certificate subject.
+	CertFieldOrganization CertificateField = "O"
+	// CertFieldOrganizationalUnit is an Organizational Unit (OU) of the certificate subject.
+	CertFieldOrganizationalUnit CertificateField = "OU"
+)
+
+// Attribute keys available on the Principal returned by MTLSAuthenticator through
+// Principal.Attribute.
+const (
+	// AttrCertificate is the verified leaf *x509.Certificate presented by the client.
+	AttrCertificate = "mtls.certificate"
+	// AttrSPIFFEID is the SPIFFE ID of the client if the certificate has a spiffe:// URI SAN.
+	AttrSPIFFEID = "mtls.spiffe_id"
+)
+
+// A CertificateRule grants roles to clients whose certificate has a field matching the pattern.
+//
+// The Pattern is matched against each value of the Field. A Pattern ending in "*" matches any
+// value with the preceding prefix, for example "spiffe://example.org/ns/payments/*", otherwise the
+// value must match exactly.
+type CertificateRule struct {
+	Field   CertificateField
+	Pattern string
+	Roles   []string
+}
+
+func (cr CertificateRule) matches(cert *x509.Certificate) bool {
+	for _, val := range certificateValues(cert, cr.Field) {
+		if prefix, ok := strings.CutSuffix(cr.Pattern, "*"); ok {
+			if strings.HasPrefix(val, prefix) {
+				return true
+			}
+			continue
+		}
+		if val == cr.Pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// MTLSAuthenticator is an HttpAuthenticator that authenticates clients using the client certificate
+// presented during the TLS handshake.
+//
+// Only certificates that were verified against the client CA pool are considered, which requires the
+// main HTTP server to be configured with WithClientCAs and WithTLS (or StartTLS). If the request
+// was not made over TLS, or the client didn't present a verified certificate, an anonymous Principal
+// is returned so the Authenticated and RequireRole middleware can be used to enforce authentication.
+//
+// The zero value is ready to use and identifies clients by their SPIFFE ID, falling back to the
+// Common Name, and then the first DNS name in the certificate.
+type MTLSAuthenticator struct {
+	// Identity is the certificate field used for the Name and SubjectID of the Principal. If empty,
+	// the first of the URI SAN, Common Name, and DNS SAN present in the certificate is used.
+	Identity CertificateField
+
+	// Rules grant roles to the Principal based on the fields of the certificate. All matching rules
+	// contribute roles.
+	Rules []CertificateRule
+}
+
+var _ HttpAuthenticator = (*MTLSAuthenticator)(nil)
+
+// NewMTLSAuthenticator creates an MTLSAuthenticator that grants roles using the provided rules.
+func NewMTLSAuthenticator(rules ...CertificateRule) *MTLSAuthenticator {
+	return &MTLSAuthenticator{
+		Rules: rules,
+	}
+}
+
+func (a *MTLSAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nopPrincipal{}, nil
+	}
+
+	cert := r.TLS.VerifiedChains[0][0]
+
+	identity := a.identity(cert)
+	if identity == "" {
+		return nopPrincipal{}, nil
+	}
+
+	roles := make(map[string]struct{})
+	for _, rule := range a.Rules {
+		if rule.matches(cert) {
+			for _, role := range rule.Roles {
+				roles[role] = struct{}{}
+			}
+		}
+	}
+
+	attrs := map[string]any{
+		AttrCertificate: cert,
+	}
+	for _, uri := range cert.URIs {
+		if uri.Scheme == "spiffe" {
+			attrs[AttrSPIFFEID] = uri.String()
+			break
+		}
+	}
+
+	return &certPrincipal{
+		name:  identity,
+		roles: roles,
+		attrs: attrs,
+	}, nil
+}
+
+func (a *MTLSAuthenticator) identity(cert *x509.Certificate) string {
+	fields := []CertificateField{CertFieldURI, CertFieldCommonName, CertFieldDNSName}
+	if a.Identity != "" {
+		fields = []CertificateField{a.Identity}
+	}
+	for _, field := range fields {
+		if vals := certificateValues(cert, field); len(vals) > 0 && vals[0] != "" {
+			return vals[0]
+		}
+	}
+	return ""
+}
+
+func certificateValues(cert *x509.Certificate, field CertificateField) []string {
+	switch field {
+	case CertFieldCommonName:
+		if cert.Subject.CommonName == "" {
+			return nil
+		}
+		return []string{cert.Subject.CommonName}
+	case CertFieldURI:
+		uris := make([]string, 0, len(cert.URIs))
+		for _, uri := range cert.URIs {
+			uris = append(uris, uri.String())
+		}
+		return uris
+	case CertFieldDNSName:
+		return cert.DNSNames
+	case CertFieldOrganization:
+		return cert.Subject.Organization
+	case CertFieldOrganizationalUnit:
+		return cert.Subject.OrganizationalUnit
+	default:
+		return nil
+	}
+}
+
+type certPrincipal struct {
+	name  string
+	roles map[string]struct{}
+	attrs map[string]any
+}
+
+func (c *certPrincipal) Name() string {
+	return c.name
+}
+
+func (c *certPrincipal) SubjectID() string {
+	return c.name
+}
+
+func (c *certPrincipal) Anonymous() bool {
+	return false
+}
+
+func (c *certPrincipal) HasRole(role string) bool {
+	_, ok := c.roles[role]
+	return ok
+}
+
+func (c *certPrincipal) Attribute(key string) (any, bool) {
+	val, ok := c.attrs[key]
+	return val, ok
+}
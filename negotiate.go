@@ -0,0 +1,195 @@
+package yuna
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jkratz55/yuna/internal"
+)
+
+// mediaRange is a media range from an Accept header along with its quality value.
+type mediaRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// parseAccept parses the media ranges in an Accept header. Malformed media ranges are skipped.
+func parseAccept(accept string) []mediaRange {
+	ranges := make([]mediaRange, 0)
+	for _, part := range strings.Split(accept, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		if val, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(val, 64)
+			if err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{typ: typ, subtype: subtype, q: q})
+	}
+	return ranges
+}
+
+// negotiateContentType returns the media type from offered that best matches the Accept header,
+// following the precedence rules of RFC 9110 section 12.5.1: the quality value of an offered media
+// type is taken from the most specific media range matching it, and ties are broken by the order of
+// offered, which is the server's preference.
+//
+// If the Accept header is empty the first offered media type is returned. If none of the offered
+// media types are acceptable an empty string is returned.
+func negotiateContentType(accept string, offered []string) string {
+	if len(offered) == 0 {
+		return ""
+	}
+	if strings.TrimSpace(accept) == "" {
+		return offered[0]
+	}
+
+	ranges := parseAccept(accept)
+	best, bestQ := "", 0.0
+	for _, offer := range offered {
+		typ, subtype, _ := strings.Cut(offer, "/")
+
+		specificity, q := -1, 0.0
+		for _, mr := range ranges {
+			var s int
+			switch {
+			case mr.typ == typ && mr.subtype == subtype:
+				s = 2
+			case mr.typ == typ && mr.subtype == "*":
+				s = 1
+			case mr.typ == "*" && mr.subtype == "*":
+				s = 0
+			default:
+				continue
+			}
+			if s > specificity {
+				specificity, q = s, mr.q
+			}
+		}
+
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// Produces returns an HTTP middleware that declares the media types a route can respond with. It is
+// the counterpart of Consumes.
+//
+// If the Accept header of the request doesn't accept any of the media types, the middleware responds
+// with an HTTP 406 Not Acceptable response. Otherwise, the media types are used in place of the media
+// types of all registered codecs when ResponseBuilder negotiates the media type of the response, in
+// the order provided. Media types must be concrete media types, such as "application/json" or
+// "application/vnd.company+json", not media ranges.
+//
+// Note that providing no media types is effectively a no-op and all requests will be allowed.
+func Produces(types ...string) HttpMiddleware {
+	types = normalizeMediaTypes(types)
+	return func(next http.Handler) http.Handler {
+		if len(types) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if negotiateContentType(r.Header.Get(HeaderAccept), types) == "" {
+				addVary(w.Header(), HeaderAccept)
+				NotAcceptable().ServeHTTP(w, r)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), internal.ContextKeyProduces, types)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func producesFromCtx(ctx context.Context) []string {
+	types, _ := ctx.Value(internal.ContextKeyProduces).([]string)
+	return types
+}
+
+// Accepts returns the media type from offered that best matches the Accept header of the request,
+// following the precedence rules of RFC 9110, or an empty string if none are acceptable. If the
+// request has no Accept header the first offered media type is returned.
+func (r *Request) Accepts(offered ...string) string {
+	return negotiateContentType(r.raw.Header.Get(HeaderAccept), normalizeMediaTypes(offered))
+}
+
+// addVary adds the header name to the Vary header of the response, unless it is already present.
+func addVary(h http.Header, name string) {
+	for _, val := range h.Values(HeaderVary) {
+		for _, field := range strings.Split(val, ",") {
+			field = strings.TrimSpace(field)
+			if field == "*" || strings.EqualFold(field, name) {
+				return
+			}
+		}
+	}
+	h.Add(HeaderVary, name)
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header accepts the content coding, following
+// RFC 9110 section 12.5.3. A coding listed with q=0 isn't acceptable, and codings not listed are
+// acceptable if the header contains "*" with a non-zero quality value.
+func acceptsEncoding(acceptEncoding, coding string) bool {
+	return encodingQuality(acceptEncoding, coding) > 0
+}
+
+// negotiateEncoding returns the content coding from offered with the highest quality value in the
+// Accept-Encoding header, preferring the codings listed first in offered, or an empty string if
+// none are acceptable.
+func negotiateEncoding(acceptEncoding string, offered []string) string {
+	best, bestQ := "", 0.0
+	for _, coding := range offered {
+		if q := encodingQuality(acceptEncoding, coding); q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+// encodingQuality returns the quality value of the content coding in the Accept-Encoding header.
+// Codings not listed have the quality value of "*", or zero if "*" isn't listed either.
+func encodingQuality(acceptEncoding, coding string) float64 {
+	wildcard := 0.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.TrimSpace(name)
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, val, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(strings.TrimSpace(key), "q") {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(val), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		switch {
+		case strings.EqualFold(name, coding):
+			return q
+		case name == "*":
+			wildcard = q
+		}
+	}
+	return wildcard
+}
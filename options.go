@@ -2,8 +2,14 @@ package yuna
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"net"
 	"net/http"
+	"os"
+	"path"
+	"syscall"
 	"time"
 
 	"github.com/go-resty/resty/v2"
@@ -18,6 +24,15 @@ type config struct {
 
 	// HTTP settings
 	httpPort                int
+	listenAddress           string
+	unixSocketPath          string
+	unixSocketMode          os.FileMode
+	systemdSocketActivation bool
+	tls                     *tlsSettings
+	clientAuth              tls.ClientAuthType
+	clientCAs               *x509.CertPool
+	h2c                     bool
+	http2                   *http.HTTP2Config
 	readTimeout             time.Duration
 	readHeaderTimeout       time.Duration
 	writeTimeout            time.Duration
@@ -26,11 +41,19 @@ type config struct {
 	notFoundHandler         http.Handler
 	methodNotAllowedHandler http.Handler
 
+	// Lifecycle settings
+	shutdownSignals []os.Signal
+	shutdownTimeout time.Duration
+	preStopDelay    time.Duration
+	hookTimeout     time.Duration
+
 	// Logging
 	logger *log.Logger
 
 	// Operations HTTP settings
 	operationHTTPPort   int
+	operationListenAddr string
+	operationTLS        *tlsSettings
 	metricsEnabled      bool
 	pprofEnabled        bool
 	healthcheckEnabled  bool
@@ -44,7 +67,15 @@ type config struct {
 	meterProvider metric.MeterProvider
 
 	// Authentication settings
-	authenticator HttpAuthenticator
+	authenticator          HttpAuthenticator
+	authenticatorDecorator func(HttpAuthenticator) HttpAuthenticator
+
+	// Request handling settings
+	validator          Validator
+	codecs             []Codec
+	maxRequestBodySize int64
+	redirectHosts      []string
+	cors               *CORSPolicy
 
 	// Resty specific settings
 	onBeforeRequest    func(c *resty.Client, r *resty.Request) error
@@ -56,6 +87,15 @@ type config struct {
 func newConfig(opts ...baseOption) *config {
 	conf := &config{
 		httpPort:                8080,
+		listenAddress:           "",
+		unixSocketPath:          "",
+		unixSocketMode:          0,
+		systemdSocketActivation: false,
+		tls:                     nil,
+		clientAuth:              tls.NoClientCert,
+		clientCAs:               nil,
+		h2c:                     false,
+		http2:                   nil,
 		readTimeout:             0,
 		readHeaderTimeout:       0,
 		writeTimeout:            0,
@@ -63,8 +103,14 @@ func newConfig(opts ...baseOption) *config {
 		baseContext:             nil,
 		notFoundHandler:         wrapFn(notFound),
 		methodNotAllowedHandler: wrapFn(methodNotAllowed),
+		shutdownSignals:         []os.Signal{os.Interrupt, syscall.SIGTERM},
+		shutdownTimeout:         time.Second * 30,
+		preStopDelay:            0,
+		hookTimeout:             time.Second * 30,
 		logger:                  log.GetLogger(),
 		operationHTTPPort:       8082,
+		operationListenAddr:     "",
+		operationTLS:            nil,
 		metricsEnabled:          false,
 		pprofEnabled:            false,
 		healthcheckEnabled:      false,
@@ -73,6 +119,11 @@ func newConfig(opts ...baseOption) *config {
 		traceProvider:           otel.GetTracerProvider(),
 		meterProvider:           otel.GetMeterProvider(),
 		authenticator:           nil,
+		validator:               NewValidator(),
+		codecs:                  nil,
+		maxRequestBodySize:      0,
+		redirectHosts:           nil,
+		cors:                    nil,
 		onBeforeRequest:         func(c *resty.Client, r *resty.Request) error { return nil },
 		onAfterResponse:         func(c *resty.Client, r *resty.Response) error { return nil },
 		onClientError:           func(r *resty.Request, err error) {},
@@ -171,6 +222,104 @@ func WithHTTPPort(port int) ServerOption {
 	})
 }
 
+// WithListenAddress sets the TCP address the main HTTP server listens on, in the form "host:port".
+// When set, it takes precedence over the port set by WithHTTPPort. A port of 0 binds to a random
+// available port, which can be retrieved with Yuna.Addr once the server is started.
+func WithListenAddress(addr string) ServerOption {
+	return serverOption(func(c *config) {
+		c.listenAddress = addr
+	})
+}
+
+// WithUnixSocket configures the main HTTP server to listen on a Unix domain socket at the given path
+// instead of a TCP address. The socket file is created with the provided file mode, for example 0660.
+// A stale socket file left behind at the path is removed before listening.
+func WithUnixSocket(path string, mode os.FileMode) ServerOption {
+	return serverOption(func(c *config) {
+		c.unixSocketPath = path
+		c.unixSocketMode = mode
+	})
+}
+
+// WithSystemdSocketActivation configures Yuna to use the sockets passed by systemd socket activation
+// through the LISTEN_FDS and LISTEN_PID environment variables.
+//
+// The first socket passed is used by the main HTTP server, and the second socket, if present, is
+// used by the operations HTTP server. Sockets can also be assigned explicitly by naming them "http"
+// and "operations" with FileDescriptorName in the systemd socket unit. If the process was not socket
+// activated, Yuna falls back to listening on the configured addresses.
+func WithSystemdSocketActivation() ServerOption {
+	return serverOption(func(c *config) {
+		c.systemdSocketActivation = true
+	})
+}
+
+// WithTLS configures the main HTTP server to serve HTTPS using the certificate and key files. When
+// configured, Start, Serve, and Run serve HTTPS on the main HTTP server.
+//
+// The certificate and key files are periodically checked for changes and reloaded without dropping
+// existing connections, allowing certificates to be rotated without restarting the application. The
+// expiration of the certificate is exposed through the tls.server.certificate.expiration metric.
+//
+// TLSOption(s) can be provided to customize the minimum TLS version, cipher suites, ALPN protocols,
+// and how often the files are checked for changes.
+func WithTLS(certFile, keyFile string, opts ...TLSOption) ServerOption {
+	return serverOption(func(c *config) {
+		c.tls = newTLSSettings(certFile, keyFile, opts...)
+	})
+}
+
+// WithClientAuth sets the policy the main HTTP server follows for TLS client authentication. If
+// WithClientCAs is provided without WithClientAuth, the policy defaults to
+// tls.VerifyClientCertIfGiven.
+//
+// This has no effect unless the main HTTP server is serving HTTPS, see WithTLS and StartTLS.
+func WithClientAuth(clientAuth tls.ClientAuthType) ServerOption {
+	return serverOption(func(c *config) {
+		c.clientAuth = clientAuth
+	})
+}
+
+// WithClientCAs sets the pool of certificate authorities the main HTTP server uses to verify client
+// certificates. Combined with MTLSAuthenticator, this enables authenticating clients with mutual TLS.
+func WithClientCAs(pool *x509.CertPool) ServerOption {
+	return serverOption(func(c *config) {
+		c.clientCAs = pool
+	})
+}
+
+// WithH2C enables unencrypted HTTP/2 (h2c) on the main HTTP server. Clients can use HTTP/2 with
+// prior knowledge, or upgrade an HTTP/1.1 connection using the "Upgrade: h2c" header. This is
+// commonly needed when running behind a service mesh or proxy, such as Envoy, that speaks HTTP/2 to
+// the application without TLS.
+func WithH2C() ServerOption {
+	return serverOption(func(c *config) {
+		c.h2c = true
+	})
+}
+
+// WithHTTP2MaxConcurrentStreams sets the maximum number of concurrent streams a client may have
+// open per HTTP/2 connection. If zero, the net/http default of at least 100 is used.
+func WithHTTP2MaxConcurrentStreams(n int) ServerOption {
+	return serverOption(func(c *config) {
+		if c.http2 == nil {
+			c.http2 = &http.HTTP2Config{}
+		}
+		c.http2.MaxConcurrentStreams = n
+	})
+}
+
+// WithHTTP2MaxReadFrameSize sets the largest HTTP/2 frame the server is willing to read. Valid values
+// are between 16KiB and 16MiB. If zero, the net/http default is used.
+func WithHTTP2MaxReadFrameSize(size int) ServerOption {
+	return serverOption(func(c *config) {
+		if c.http2 == nil {
+			c.http2 = &http.HTTP2Config{}
+		}
+		c.http2.MaxReadFrameSize = size
+	})
+}
+
 // WithReadTimeout sets the server's ReadTimeout.
 func WithReadTimeout(timeout time.Duration) ServerOption {
 	return serverOption(func(c *config) {
@@ -220,6 +369,44 @@ func WithMethodNotAllowedHandler(handler http.Handler) ServerOption {
 	})
 }
 
+// WithShutdownSignals sets the OS signals that trigger a graceful shutdown when the application is
+// started with Run. The default is SIGINT and SIGTERM. Passing no signals disables signal handling,
+// so Run only shuts down once its context is cancelled.
+func WithShutdownSignals(signals ...os.Signal) ServerOption {
+	return serverOption(func(c *config) {
+		c.shutdownSignals = signals
+	})
+}
+
+// WithShutdownTimeout sets the maximum duration Run waits for in-flight requests to complete once
+// the servers begin shutting down. The default is 30 seconds.
+func WithShutdownTimeout(timeout time.Duration) ServerOption {
+	return serverOption(func(c *config) {
+		c.shutdownTimeout = timeout
+	})
+}
+
+// WithPreStopDelay sets the duration Run waits after receiving a shutdown signal before it starts
+// shutting down the servers. During this time the readiness endpoint reports DOWN, giving load
+// balancers and service meshes time to stop routing new traffic to the instance. The default is 0.
+func WithPreStopDelay(delay time.Duration) ServerOption {
+	return serverOption(func(c *config) {
+		c.preStopDelay = delay
+	})
+}
+
+// WithDefaultHookTimeout sets the default maximum duration a lifecycle hook registered with OnStart
+// or OnStop is allowed to run, which must be greater than zero. The timeout can be overridden per
+// hook with HookTimeout. The default is 30 seconds.
+func WithDefaultHookTimeout(timeout time.Duration) ServerOption {
+	if timeout <= 0 {
+		panic("default hook timeout must be greater than zero")
+	}
+	return serverOption(func(c *config) {
+		c.hookTimeout = timeout
+	})
+}
+
 // WithOperationsHttpPort sets the port for the operational server. The default is 8082.
 func WithOperationsHttpPort(port int) ServerOption {
 	return serverOption(func(c *config) {
@@ -227,6 +414,24 @@ func WithOperationsHttpPort(port int) ServerOption {
 	})
 }
 
+// WithOperationsListenAddress sets the TCP address the operations HTTP server listens on, in the form
+// "host:port". When set, it takes precedence over the port set by WithOperationsHttpPort. This is
+// useful for binding the operations server to a loopback interface, for example "127.0.0.1:8082".
+func WithOperationsListenAddress(addr string) ServerOption {
+	return serverOption(func(c *config) {
+		c.operationListenAddr = addr
+	})
+}
+
+// WithOperationsTLS configures the operations HTTP server to serve HTTPS using the certificate and
+// key files. By default, the operations HTTP server serves plain HTTP. The certificate is reloaded
+// when the files change, the same as WithTLS.
+func WithOperationsTLS(certFile, keyFile string, opts ...TLSOption) ServerOption {
+	return serverOption(func(c *config) {
+		c.operationTLS = newTLSSettings(certFile, keyFile, opts...)
+	})
+}
+
 // WithMetrics enables the Prometheus metrics endpoint on the operational server.
 func WithMetrics() ServerOption {
 	return serverOption(func(c *config) {
@@ -291,6 +496,93 @@ func WithAuthentication(authenticator HttpAuthenticator) ServerOption {
 	})
 }
 
+// WithAuthenticatorDecorator wraps the HttpAuthenticator of every Authenticate middleware handling
+// requests served by the application, including the one configured with WithAuthentication, with
+// the HttpAuthenticator returned by decorator. This can be used to audit authentication attempts,
+// or by tests to authenticate requests without credentials, as yunatest does.
+//
+// If the option is given more than once, the decorators are applied in order, so the last one
+// wraps the others.
+func WithAuthenticatorDecorator(decorator func(HttpAuthenticator) HttpAuthenticator) ServerOption {
+	if decorator == nil {
+		panic("authenticator decorator cannot be nil")
+	}
+	return serverOption(func(c *config) {
+		prev := c.authenticatorDecorator
+		if prev == nil {
+			c.authenticatorDecorator = decorator
+			return
+		}
+		c.authenticatorDecorator = func(authenticator HttpAuthenticator) HttpAuthenticator {
+			return decorator(prev(authenticator))
+		}
+	})
+}
+
+// WithValidator sets the Validator used by Request.DecodeAndValidate and Typed handlers to validate
+// requests. The default is a StructValidator, which validates structs using the `validate` struct
+// tag. Since Typed checks the rules in the `validate` tag of its request type using the syntax of
+// StructValidator, a Validator with a different syntax should read a different struct tag.
+func WithValidator(validator Validator) ServerOption {
+	if validator == nil {
+		panic("validator cannot be nil")
+	}
+	return serverOption(func(c *config) {
+		c.validator = validator
+	})
+}
+
+// WithCodecs registers codecs used to decode request bodies and encode response bodies, in addition
+// to the default codecs for JSON, XML, msgpack, and plain text. A Codec handling the same media type as a
+// default codec replaces it, for example to use a faster JSON library. The codecs are offered in the
+// order they were registered when negotiating the media type of a response, after the default
+// codecs.
+func WithCodecs(codecs ...Codec) ServerOption {
+	return serverOption(func(c *config) {
+		c.codecs = append(c.codecs, codecs...)
+	})
+}
+
+// WithMaxRequestBodySize limits the size of request bodies to n bytes for all routes using the
+// MaxBodySize middleware. Requests with larger bodies receive a 413 Content Too Large problem. The
+// limit can be raised or lowered for specific routes using MaxBodySize. The default is no limit.
+func WithMaxRequestBodySize(n int64) ServerOption {
+	return serverOption(func(c *config) {
+		c.maxRequestBodySize = n
+	})
+}
+
+// WithCORS enforces the Cross-Origin Resource Sharing policy for all routes using the CORS
+// middleware, allowing browsers to call the API from the origins allowed by the policy. Preflight
+// requests are answered before authentication, since browsers never send credentials with them. By
+// default, no CORS policy is enforced, so browsers block cross-origin requests. See CORS for
+// applying policies to specific routes.
+//
+// WithCORS panics if the policy is invalid, see CORS.
+func WithCORS(policy CORSPolicy) ServerOption {
+	newCORS(policy)
+	return serverOption(func(c *config) {
+		c.cors = &policy
+	})
+}
+
+// WithRedirectHosts sets the hosts Redirect may send clients to, in addition to the host of the
+// request. The patterns are matched against the host of the redirect target, with or without the
+// port, using path.Match, for example "login.example.com" or "*.example.com". Redirects to other
+// hosts receive a 400 Bad Request problem, which prevents open redirects when the target is taken
+// from the request. By default, only relative redirects and redirects to the host of the request
+// are allowed.
+func WithRedirectHosts(patterns ...string) ServerOption {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			panic(fmt.Sprintf("invalid redirect host pattern %q", pattern))
+		}
+	}
+	return serverOption(func(c *config) {
+		c.redirectHosts = append(c.redirectHosts, patterns...)
+	})
+}
+
 // ------------------------------------------------------------------------------------------------
 // Client Options
 // ------------------------------------------------------------------------------------------------
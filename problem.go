@@ -120,6 +120,14 @@ func (p *ProblemDetails) MarshalJSON() ([]byte, error) {
 		return nil, err
 	}
 
+	// Violations are written after the core fields as they are reserved and not treated as a
+	// regular extension.
+	if violations := p.Violations(); len(violations) > 0 {
+		if err := writeKV("violations", violations, &first); err != nil {
+			return nil, err
+		}
+	}
+
 	// Extensions appended in sorted key order for determinism
 	if len(p.Extensions) > 0 {
 		keys := make([]string, 0, len(p.Extensions))
@@ -141,6 +149,50 @@ func (p *ProblemDetails) MarshalJSON() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// UnmarshalJSON decodes a problem details object, for example one returned by another service. Any
+// members other than the ones defined by RFC 9457 are decoded into Extensions.
+func (p *ProblemDetails) UnmarshalJSON(data []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+
+	p.Extensions = make(map[string]interface{})
+	for key, raw := range members {
+		var err error
+		switch key {
+		case "type":
+			err = json.Unmarshal(raw, &p.Type)
+		case "title":
+			err = json.Unmarshal(raw, &p.Title)
+		case "detail":
+			err = json.Unmarshal(raw, &p.Detail)
+		case "instance":
+			err = json.Unmarshal(raw, &p.Instance)
+		case "status":
+			err = json.Unmarshal(raw, &p.StatusCode)
+		case "violations":
+			var violations Violations
+			err = json.Unmarshal(raw, &violations)
+			p.Extensions[key] = violations
+		default:
+			var val interface{}
+			err = json.Unmarshal(raw, &val)
+			p.Extensions[key] = val
+		}
+		if err != nil {
+			return fmt.Errorf("problem details member %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// Violations returns the validation errors / constraint violations attached to the problem, if any.
+func (p *ProblemDetails) Violations() Violations {
+	violations, _ := p.Extensions["violations"].(Violations)
+	return violations
+}
+
 func (p *ProblemDetails) Error() string {
 	if p.error != nil {
 		return p.error.Error()
@@ -155,6 +207,33 @@ func (p *ProblemDetails) Respond(w http.ResponseWriter, r *http.Request) error {
 
 func (p *ProblemDetails) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
+	p.populate(r)
+
+	for k, v := range p.headers {
+		for _, h := range v {
+			w.Header().Add(k, h)
+		}
+	}
+
+	// The problem is encoded with the Codec registered for JSON unless a Codec was registered for
+	// application/problem+json specifically.
+	codec, _ := settingsFromCtx(r.Context()).codecs.lookup(MIMEApplicationProblemJSON)
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Set("Content-Type", MIMEApplicationProblemJSON)
+	w.WriteHeader(p.StatusCode)
+	if err := codec.Encode(w, p); err != nil {
+		log.LoggerFromCtx(r.Context()).Error("Error writing problem/error response to client",
+			log.Error(err))
+	}
+}
+
+// populate fills in the members of the problem derived from the request, such as the instance and
+// the IDs correlating the problem with the request logs and traces.
+func (p *ProblemDetails) populate(r *http.Request) {
 	// Default to 500 Internal Server Error if no status code is set.
 	if p.StatusCode == 0 {
 		p.StatusCode = http.StatusInternalServerError
@@ -182,20 +261,6 @@ func (p *ProblemDetails) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		p.Extensions["traceId"] = spanCtx.TraceID().String()
 		p.Extensions["sampled"] = spanCtx.IsSampled()
 	}
-
-	for k, v := range p.headers {
-		for _, h := range v {
-			w.Header().Add(k, h)
-		}
-	}
-
-	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-	w.Header().Set("Content-Type", "application/problem+json")
-	w.WriteHeader(p.StatusCode)
-	if err := json.NewEncoder(w).Encode(p); err != nil {
-		log.LoggerFromCtx(r.Context()).Error("Error writing problem/error response to client",
-			log.Error(err))
-	}
 }
 
 func BadRequest(violations Violations) *ProblemDetails {
@@ -289,6 +354,26 @@ func Gone() *ProblemDetails {
 	}
 }
 
+func PreconditionFailed() *ProblemDetails {
+	return &ProblemDetails{
+		Type:       "about:blank",
+		Title:      "Precondition Failed",
+		Detail:     "The resource has been modified since it was retrieved.",
+		StatusCode: http.StatusPreconditionFailed,
+		Extensions: make(map[string]interface{}),
+	}
+}
+
+func ContentTooLarge() *ProblemDetails {
+	return &ProblemDetails{
+		Type:       "about:blank",
+		Title:      "Content Too Large",
+		Detail:     "The request content is larger than the server is willing or able to process.",
+		StatusCode: http.StatusRequestEntityTooLarge,
+		Extensions: make(map[string]interface{}),
+	}
+}
+
 func UnsupportedMediaType() *ProblemDetails {
 	return &ProblemDetails{
 		Type:       "about:blank",
@@ -319,6 +404,16 @@ func UnprocessableEntity(violations Violations) *ProblemDetails {
 	return prob
 }
 
+func PreconditionRequired() *ProblemDetails {
+	return &ProblemDetails{
+		Type:       "about:blank",
+		Title:      "Precondition Required",
+		Detail:     "The request must be conditional, include the ETag of the resource in the If-Match header.",
+		StatusCode: http.StatusPreconditionRequired,
+		Extensions: make(map[string]interface{}),
+	}
+}
+
 func TooManyRequests() *ProblemDetails {
 	return &ProblemDetails{
 		Type:       "about:blank",
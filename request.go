@@ -2,22 +2,14 @@ package yuna
 
 import (
 	"context"
-	"encoding/json"
-	"encoding/xml"
+	"errors"
+	"fmt"
 	"io"
-	"mime"
 	"mime/multipart"
 	"net/http"
 	"net/url"
-	"strings"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-playground/form/v4"
-	"github.com/vmihailenco/msgpack/v5"
-)
-
-var (
-	decoder = form.NewDecoder()
 )
 
 type Request struct {
@@ -63,11 +55,6 @@ func (r *Request) ParseForm() error {
 	return r.raw.ParseForm()
 }
 
-func (r *Request) Bind(dst any) error {
-	_ = r.raw.ParseForm()
-	return decoder.Decode(dst, r.raw.URL.Query())
-}
-
 func (r *Request) Header(name string) string {
 	return r.raw.Header.Get(name)
 }
@@ -160,19 +147,86 @@ func (r *Request) MultipartReader() (*multipart.Reader, error) {
 	return r.raw.MultipartReader()
 }
 
+// Decode decodes the request body into v using the Codec registered for the Content-Type of the
+// request. Requests without a Content-Type are decoded as JSON. If no Codec is registered for the
+// Content-Type an error wrapping ErrUnsupportedMediaType is returned.
 func (r *Request) Decode(v interface{}) error {
-	ct := r.raw.Header.Get(HeaderContentType)
-	mediaType, _, _ := mime.ParseMediaType(ct)
-	mediaType = strings.ToLower(mediaType)
-
-	switch mediaType {
-	case MIMEApplicationMsgpack:
-		return msgpack.NewDecoder(r.raw.Body).Decode(v)
-	case MIMEApplicationXML, MIMEApplicationXMLCharsetUTF8, MIMETextXML, MIMETextXMLCharsetUTF8:
-		return xml.NewDecoder(r.raw.Body).Decode(v)
+	codec, err := settingsFromCtx(r.raw.Context()).codecs.forContentType(r.raw.Header.Get(HeaderContentType))
+	if err != nil {
+		return err
+	}
+	return codec.Decode(r.raw.Body, v)
+}
+
+// DecodeAndValidate decodes the request body into v using Decode and then validates it using the
+// Validator configured with WithValidator, and the Validate method of v if it implements
+// Validatable.
+//
+// If the body cannot be decoded a 400 Bad Request problem is returned, or a 415 Unsupported Media
+// Type problem if the Content-Type isn't supported. If v isn't valid a 422
+// Unprocessable Entity problem with the Violations is returned. The names of the fields in the
+// Violations follow the struct tags used to decode the body, json, xml or msgpack, so they match the
+// names sent by the client. If v is valid nil is returned.
+func (r *Request) DecodeAndValidate(v any) *ProblemDetails {
+	if err := r.Decode(v); err != nil {
+		return decodeProblem(err)
+	}
+	return r.validate(v)
+}
+
+// validate validates the value pointed to by v, returning a 422 Unprocessable Entity problem if
+// it isn't valid.
+func (r *Request) validate(v any) *ProblemDetails {
+	settings := settingsFromCtx(r.raw.Context())
+	nameTag := settings.codecs.nameTag(r.raw.Header.Get(HeaderContentType))
+	violations := validateValue(settings.validator, v, nameTag)
+	if len(violations) > 0 {
+		return UnprocessableEntity(violations)
+	}
+	return nil
+}
+
+// decodeProblem returns the problem sent to the client when the request body cannot be decoded.
+func decodeProblem(err error) *ProblemDetails {
+	var (
+		maxBytesErr *http.MaxBytesError
+		decodeErr   *DecodeError
+	)
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return ContentTooLarge().
+			SetDetail(fmt.Sprintf("The request content exceeds the limit of %d bytes.", maxBytesErr.Limit)).
+			SetError(err)
+	case errors.Is(err, ErrUnsupportedMediaType):
+		return UnsupportedMediaType().SetError(err)
+	case errors.As(err, &decodeErr):
+		if decodeErr.Field != "" {
+			return BadRequest(Violations{decodeErr.Field: {decodeErr.Msg}}).SetError(err)
+		}
+		prob := BadRequest(nil).SetDetail(decodeErrorDetail(decodeErr)).SetError(err)
+		if decodeErr.Offset > 0 {
+			prob.AddExtension("offset", decodeErr.Offset)
+		}
+		if decodeErr.Line > 0 {
+			prob.AddExtension("line", decodeErr.Line)
+		}
+		return prob
+	case errors.Is(err, io.EOF):
+		return BadRequest(nil).SetDetail("The request body is empty.").SetError(err)
+	default:
+		return BadRequest(nil).SetError(err)
+	}
+}
+
+// decodeErrorDetail describes where a request body is malformed for the detail of a problem.
+func decodeErrorDetail(err *DecodeError) string {
+	switch {
+	case err.Line > 0:
+		return fmt.Sprintf("The request body is malformed at line %d: %s.", err.Line, err.Msg)
+	case err.Offset > 0:
+		return fmt.Sprintf("The request body is malformed at offset %d: %s.", err.Offset, err.Msg)
 	default:
-		// If we don't match any of the above content types, it is assumed to be JSON.
-		return json.NewDecoder(r.raw.Body).Decode(v)
+		return fmt.Sprintf("The request body is malformed: %s.", err.Msg)
 	}
 }
 
@@ -2,9 +2,19 @@ package yuna
 
 import (
 	"bytes"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"html/template"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jkratz55/yuna/log"
 )
 
 // A Responder responds to an HTTP request.
@@ -24,7 +34,16 @@ type ResponseBuilder struct {
 	header  http.Header
 	cookies []*http.Cookie
 	body    any
-	html    bool
+
+	lastModified time.Time
+
+	// raw is written to the client as is instead of encoding body, see Text, Bytes, and Reader.
+	raw       io.Reader
+	rawType   string
+	rawLength int64
+
+	redirect   bool
+	redirectTo string
 }
 
 func Response() *ResponseBuilder {
@@ -33,7 +52,6 @@ func Response() *ResponseBuilder {
 		header:  http.Header{},
 		cookies: make([]*http.Cookie, 0),
 		body:    nil,
-		html:    false,
 	}
 }
 
@@ -68,8 +86,31 @@ func (rb *ResponseBuilder) Cookies(cookies []*http.Cookie) *ResponseBuilder {
 	return rb
 }
 
+// ETag sets the entity tag of the response. The tag is quoted if it isn't already. If the request
+// is a GET or HEAD request whose If-None-Match header matches the tag, 304 Not Modified is sent
+// instead of the body, and if its If-Match header doesn't match the tag, 412 Precondition Failed.
+func (rb *ResponseBuilder) ETag(tag string, weak bool) *ResponseBuilder {
+	etag := quoteETag(tag)
+	if weak && !strings.HasPrefix(etag, "W/") {
+		etag = "W/" + etag
+	}
+	rb.header.Set(HeaderETag, etag)
+	return rb
+}
+
+// LastModified sets the modification time of the response. If the request is a GET or HEAD request
+// with an If-Modified-Since header at or after t, and without an If-None-Match header, 304 Not
+// Modified is sent instead of the body. If it has an If-Unmodified-Since header before t, and no
+// If-Match header, 412 Precondition Failed is sent.
+func (rb *ResponseBuilder) LastModified(t time.Time) *ResponseBuilder {
+	rb.lastModified = t
+	rb.header.Set(HeaderLastModified, t.UTC().Format(http.TimeFormat))
+	return rb
+}
+
 func (rb *ResponseBuilder) Body(body any) *ResponseBuilder {
 	rb.body = body
+	rb.raw = nil
 	return rb
 }
 
@@ -86,23 +127,124 @@ func (rb *ResponseBuilder) Html(tpl *template.Template, name string, data any) *
 		panic(err)
 	}
 
-	rb.body = buf.Bytes()
-	rb.html = true
+	return rb.Bytes(MIMETextHTMLCharsetUTF8, buf.Bytes())
+}
+
+// Text sets the body of the response to the text, sent as text/plain.
+func (rb *ResponseBuilder) Text(text string) *ResponseBuilder {
+	return rb.Bytes(MIMETextPlainCharsetUTF8, []byte(text))
+}
+
+// Bytes sets the body of the response to data, sent as is with the Content-Type, instead of being
+// encoded by a Codec.
+func (rb *ResponseBuilder) Bytes(contentType string, data []byte) *ResponseBuilder {
+	rb.Reader(contentType, bytes.NewReader(data))
+	rb.rawLength = int64(len(data))
+	return rb
+}
+
+// Reader sets the body of the response to the content read from r, streamed to the client as is
+// with the Content-Type. If r implements io.Closer it is closed after responding.
+func (rb *ResponseBuilder) Reader(contentType string, r io.Reader) *ResponseBuilder {
+	if r == nil {
+		panic("reader cannot be nil")
+	}
+	rb.body = nil
+	rb.raw = r
+	rb.rawType = contentType
+	rb.rawLength = -1
 	return rb
 }
 
+// Respond writes the response to the client.
+//
+// The body is encoded using the Codec registered for the Content-Type header if one was set.
+// Otherwise, the media type is negotiated with the Accept header of the request, following RFC 9110,
+// from the media types of the registered codecs, or the media types declared with Produces. If none
+// of the media types are acceptable to the client, a 406 Not Acceptable problem is sent instead.
 func (rb *ResponseBuilder) Respond(w http.ResponseWriter, r *http.Request) error {
+	if rb.raw != nil {
+		if closer, ok := rb.raw.(io.Closer); ok {
+			defer closer.Close()
+		}
+	}
 
-	// todo: only supporting JSON and HTML for now, but we should handle content negotiation
+	if rb.redirect {
+		if err := checkRedirect(r, rb.redirectTo); err != nil {
+			log.LoggerFromCtx(r.Context()).Warn("Refusing to redirect to a target that isn't allowed",
+				log.String("target", rb.redirectTo), log.Error(err))
+			return BadRequest(nil).SetDetail("The redirect target is not allowed.").Respond(w, r)
+		}
+	}
 
-	if rb.status == 0 && rb.body != nil {
+	hasBody := rb.body != nil || rb.raw != nil
+	if rb.status == 0 && hasBody {
 		rb.status = http.StatusOK
 	}
-	if rb.status == 0 && rb.body == nil {
+	if rb.status == 0 && !hasBody {
 		rb.status = http.StatusNoContent
 	}
 
-	// Transpose headers to ResponseWriter
+	// The preconditions are evaluated once the handler ran, which is only sound for safe methods.
+	if rb.status == http.StatusOK && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+		switch checkPreconditions(r, rb.header.Get(HeaderETag), rb.lastModified) {
+		case http.StatusNotModified:
+			rb.writeHeaders(w)
+			writeNotModified(w)
+			return nil
+		case http.StatusPreconditionFailed:
+			return PreconditionFailed().Respond(w, r)
+		}
+	}
+
+	var (
+		codec     Codec
+		mediaType string
+	)
+	if rb.body != nil {
+		var err error
+		codec, mediaType, err = rb.codec(w, r)
+		if err != nil {
+			return err
+		}
+		if codec == nil {
+			return NotAcceptable().Respond(w, r)
+		}
+	}
+
+	rb.writeHeaders(w)
+
+	if rb.redirect {
+		w.Header().Set(HeaderLocation, rb.redirectTo)
+	}
+
+	if rb.raw != nil {
+		if w.Header().Get(HeaderContentType) == "" && rb.rawType != "" {
+			w.Header().Set(HeaderContentType, rb.rawType)
+		}
+		if rb.rawLength >= 0 && w.Header().Get(HeaderContentLength) == "" {
+			w.Header().Set(HeaderContentLength, strconv.FormatInt(rb.rawLength, 10))
+		}
+		w.WriteHeader(rb.status)
+		_, err := io.Copy(w, rb.raw)
+		return err
+	}
+
+	if rb.body == nil {
+		w.WriteHeader(rb.status)
+		return nil
+	}
+
+	if w.Header().Get(HeaderContentType) == "" {
+		w.Header().Set(HeaderContentType, contentType(mediaType))
+	}
+
+	w.WriteHeader(rb.status)
+	return codec.Encode(w, rb.body)
+}
+
+// writeHeaders transposes the headers and cookies to the ResponseWriter.
+func (rb *ResponseBuilder) writeHeaders(w http.ResponseWriter) {
 	for key, values := range rb.header {
 		for _, value := range values {
 			w.Header().Add(key, value)
@@ -112,20 +254,35 @@ func (rb *ResponseBuilder) Respond(w http.ResponseWriter, r *http.Request) error
 	for _, cookie := range rb.cookies {
 		http.SetCookie(w, cookie)
 	}
+}
 
-	if rb.html {
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	} else {
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+// codec returns the Codec used to encode the body and the media type it is encoded as. If the media
+// type is negotiated, Vary: Accept is added to the response, and a nil Codec is returned if none of
+// the media types are acceptable.
+func (rb *ResponseBuilder) codec(w http.ResponseWriter, r *http.Request) (Codec, string, error) {
+	codecs := settingsFromCtx(r.Context()).codecs
+
+	if ct := rb.header.Get(HeaderContentType); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid Content-Type %q: %w", ct, err)
+		}
+		codec, ok := codecs.lookup(mediaType)
+		if !ok {
+			return nil, "", fmt.Errorf("no codec registered for Content-Type %s", mediaType)
+		}
+		return codec, mediaType, nil
 	}
 
-	w.WriteHeader(rb.status)
+	addVary(w.Header(), HeaderAccept)
 
-	if rb.body == nil {
-		return nil
+	offered := codecs.offered(rb.body, producesFromCtx(r.Context()))
+	mediaType := negotiateContentType(r.Header.Get(HeaderAccept), offered)
+	if mediaType == "" {
+		return nil, "", nil
 	}
-
-	return json.NewEncoder(w).Encode(rb.body)
+	codec, _ := codecs.lookup(mediaType)
+	return codec, mediaType, nil
 }
 
 func Ok(body any) *ResponseBuilder {
@@ -165,3 +322,86 @@ func NoContent() *ResponseBuilder {
 		cookies: make([]*http.Cookie, 0),
 	}
 }
+
+// Text returns a Responder sending the text as text/plain with the status 200 OK.
+func Text(text string) *ResponseBuilder {
+	return Response().Status(http.StatusOK).Text(text)
+}
+
+// Bytes returns a Responder sending data as is with the Content-Type and the status 200 OK.
+func Bytes(contentType string, data []byte) *ResponseBuilder {
+	return Response().Status(http.StatusOK).Bytes(contentType, data)
+}
+
+// Reader returns a Responder streaming the content read from r with the Content-Type and the status
+// 200 OK. If r implements io.Closer it is closed after responding.
+func Reader(contentType string, r io.Reader) *ResponseBuilder {
+	return Response().Status(http.StatusOK).Reader(contentType, r)
+}
+
+// Redirect returns a Responder redirecting the client to the target URL with the status, which must
+// be one of 301 Moved Permanently, 302 Found, 303 See Other, 307 Temporary Redirect, or 308
+// Permanent Redirect.
+//
+// Relative targets, and targets on the host of the request, are always allowed. Targets on other
+// hosts must be allowed with WithRedirectHosts, otherwise a 400 Bad Request problem is sent
+// instead. This prevents open redirects when the target is taken from the request, such as a
+// ?next= query parameter of a login page.
+func Redirect(status int, target string) *ResponseBuilder {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		panic(fmt.Sprintf("invalid redirect status %d", status))
+	}
+	rb := Response().Status(status)
+	rb.redirect = true
+	rb.redirectTo = target
+	return rb
+}
+
+// checkRedirect returns an error if the target of a redirect isn't a valid http or https URL, or if
+// it is on a host that isn't allowed.
+func checkRedirect(r *http.Request, target string) error {
+	if target == "" {
+		return errors.New("empty target")
+	}
+	// Browsers treat backslashes as slashes, so /\evil.com would redirect to another host.
+	if strings.Contains(target, `\`) {
+		return errors.New("target contains a backslash")
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("scheme %s isn't allowed", u.Scheme)
+	}
+	// Browsers are lenient with absolute URLs missing the slashes, such as https:evil.com or
+	// https:///evil.com, which are rejected since they are never intended.
+	if u.Scheme != "" && (u.Opaque != "" || u.Host == "") {
+		return errors.New("absolute target without host")
+	}
+	if u.Host == "" {
+		return nil
+	}
+	if u.User != nil {
+		return errors.New("target contains user info")
+	}
+
+	host := strings.ToLower(u.Host)
+	if host == strings.ToLower(r.Host) {
+		return nil
+	}
+	hostname := strings.ToLower(u.Hostname())
+	for _, pattern := range settingsFromCtx(r.Context()).redirectHosts {
+		pattern = strings.ToLower(pattern)
+		if ok, _ := path.Match(pattern, host); ok {
+			return nil
+		}
+		if ok, _ := path.Match(pattern, hostname); ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %s isn't allowed", u.Host)
+}
@@ -1,6 +1,7 @@
 package yuna
 
 import (
+	"io/fs"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -30,11 +31,57 @@ type Router interface {
 
 type Mux struct {
 	r chi.Router
+
+	// base is the router sharing the routing tree of r without the middleware added by With and
+	// Group, on which the preflight routes for the CORS policy of the Mux are registered.
+	base       chi.Router
+	cors       *cors
+	preflights preflightRoutes
 }
 
 func NewMux() *Mux {
+	return newMux(chi.NewRouter())
+}
+
+func newMux(r chi.Router) *Mux {
 	return &Mux{
-		r: chi.NewRouter(),
+		r:          r,
+		base:       r,
+		preflights: make(preflightRoutes),
+	}
+}
+
+// inline returns a Mux for the router r sharing the routing tree of m, such as the routers created
+// by With and Group.
+func (m *Mux) inline(r chi.Router, middleware ...func(http.Handler) http.Handler) *Mux {
+	return &Mux{
+		r:          r,
+		base:       m.base,
+		cors:       withCORSPolicy(m.cors, middleware...),
+		preflights: m.preflights,
+	}
+}
+
+// withCORSPolicy returns the CORS policy of the last CORS middleware, or policy if there is none.
+func withCORSPolicy(policy *cors, middleware ...func(http.Handler) http.Handler) *cors {
+	for _, mw := range middleware {
+		if c := corsPolicy(mw); c != nil {
+			policy = c
+		}
+	}
+	return policy
+}
+
+// handle registers the handler for the method and pattern. Routes with a CORS policy applied by
+// With or Group also get an OPTIONS route answering their preflight requests, unless the
+// application registers an OPTIONS route for the pattern itself.
+func (m *Mux) handle(method, pattern string, handler http.Handler) {
+	m.r.Method(method, pattern, handler)
+	switch {
+	case method == http.MethodOptions:
+		m.preflights.explicit(pattern)
+	case m.cors != nil:
+		m.preflights.add(m.base, method, pattern, m.cors)
 	}
 }
 
@@ -44,51 +91,55 @@ func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 func (m *Mux) Use(middleware ...func(http.Handler) http.Handler) {
 	m.r.Use(middleware...)
+	// Middleware used by a Group only applies to its routes, just like middleware added by With.
+	if m.r != m.base {
+		m.cors = withCORSPolicy(m.cors, middleware...)
+	}
 }
 
 func (m *Mux) With(middleware ...func(http.Handler) http.Handler) Router {
 	router := m.r.With(middleware...)
-	return &Mux{r: router}
+	return m.inline(router, middleware...)
 }
 
 func (m *Mux) Get(pattern string, fn HandlerFunc, middleware ...HttpMiddleware) {
-	m.r.Get(pattern, wrapFn(fn, middleware...))
+	m.handle(http.MethodGet, pattern, wrap(fn, middleware...))
 }
 
 func (m *Mux) Post(pattern string, fn HandlerFunc, middleware ...HttpMiddleware) {
-	m.r.Post(pattern, wrapFn(fn, middleware...))
+	m.handle(http.MethodPost, pattern, wrap(fn, middleware...))
 }
 
 func (m *Mux) Put(pattern string, fn HandlerFunc, middleware ...HttpMiddleware) {
-	m.r.Put(pattern, wrapFn(fn, middleware...))
+	m.handle(http.MethodPut, pattern, wrap(fn, middleware...))
 }
 
 func (m *Mux) Delete(pattern string, fn HandlerFunc, middleware ...HttpMiddleware) {
-	m.r.Delete(pattern, wrapFn(fn, middleware...))
+	m.handle(http.MethodDelete, pattern, wrap(fn, middleware...))
 }
 
 func (m *Mux) Patch(pattern string, fn HandlerFunc, middleware ...HttpMiddleware) {
-	m.r.Patch(pattern, wrapFn(fn, middleware...))
+	m.handle(http.MethodPatch, pattern, wrap(fn, middleware...))
 }
 
 func (m *Mux) Options(pattern string, fn HandlerFunc, middleware ...HttpMiddleware) {
-	m.r.Options(pattern, wrapFn(fn, middleware...))
+	m.handle(http.MethodOptions, pattern, wrap(fn, middleware...))
 }
 
 func (m *Mux) Head(pattern string, fn HandlerFunc, middleware ...HttpMiddleware) {
-	m.r.Head(pattern, wrapFn(fn, middleware...))
+	m.handle(http.MethodHead, pattern, wrap(fn, middleware...))
 }
 
 func (m *Mux) Connect(pattern string, fn HandlerFunc, middleware ...HttpMiddleware) {
-	m.r.Connect(pattern, wrapFn(fn, middleware...))
+	m.handle(http.MethodConnect, pattern, wrap(fn, middleware...))
 }
 
 func (m *Mux) Trace(pattern string, fn HandlerFunc, middleware ...HttpMiddleware) {
-	m.r.Trace(pattern, wrapFn(fn, middleware...))
+	m.handle(http.MethodTrace, pattern, wrap(fn, middleware...))
 }
 
 func (m *Mux) Method(method string, pattern string, handler Handler, middleware ...HttpMiddleware) {
-	m.r.Method(method, pattern, wrap(handler, middleware...))
+	m.handle(method, pattern, wrap(handler, middleware...))
 }
 
 func (m *Mux) Mount(pattern string, h http.Handler) {
@@ -97,12 +148,53 @@ func (m *Mux) Mount(pattern string, h http.Handler) {
 
 func (m *Mux) Route(pattern string, fn func(r Router)) {
 	m.r.Route(pattern, func(r chi.Router) {
-		fn(&Mux{r: r})
+		sub := newMux(r)
+		sub.cors = m.cors
+		fn(sub)
 	})
 }
 
 func (m *Mux) Group(fn func(r Router)) {
 	m.r.Group(func(r chi.Router) {
-		fn(&Mux{r: r})
+		fn(m.inline(r))
 	})
 }
+
+// Static serves the files of fsys, such as an embed.FS or os.DirFS, for GET and HEAD requests
+// to paths under prefix. Requests for directories are served the index file of the directory,
+// and requests for missing files receive a 404 Not Found problem. Files are sent using
+// FileResponder, supporting conditional and range requests, with an ETag derived from their
+// content. If a precompressed variant of a file exists with a .br or .gz extension, it is sent
+// instead when the client accepts that encoding. Files and directories starting with a dot are
+// never served, except the .well-known directory defined by RFC 8615.
+//
+// Static isn't part of the Router interface. The Router passed to the functions given to Route and
+// Group is a *Mux, so files can be served from a group using r.(*yuna.Mux).Static.
+//
+// All files are reported with the route pattern prefix/* as the http.route in traces and
+// metrics.
+//
+//	//go:embed dist
+//	var dist embed.FS
+//
+//	assets, _ := fs.Sub(dist, "dist")
+//	mux.Static("/", assets, yuna.StaticSPA())
+func (m *Mux) Static(prefix string, fsys fs.FS, opts ...StaticOption) {
+	static(m.r, prefix, fsys, opts...)
+}
+
+func (m *Mux) Routes() []chi.Route {
+	return m.r.Routes()
+}
+
+func (m *Mux) Middlewares() chi.Middlewares {
+	return m.r.Middlewares()
+}
+
+func (m *Mux) Match(rctx *chi.Context, method, path string) bool {
+	return m.r.Match(rctx, method, path)
+}
+
+func (m *Mux) Find(rctx *chi.Context, method, path string) string {
+	return m.r.Find(rctx, method, path)
+}
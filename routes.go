@@ -0,0 +1,178 @@
+package yuna
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RouteInfo describes a route registered with Yuna.
+type RouteInfo struct {
+	Method  string `json:"method"`
+	Pattern string `json:"pattern"`
+
+	// Middlewares are the names of the middleware applied to the route, in the order they are
+	// invoked, including the middleware applied globally with Use.
+	Middlewares []string `json:"middlewares"`
+
+	// Protected is true when the route is guarded by the Authenticated or RequireRole middleware.
+	Protected bool `json:"protected"`
+
+	// Roles are the roles required by RequireRole middleware guarding the route.
+	Roles []string `json:"roles,omitempty"`
+}
+
+// RouteInventory returns every route registered with Yuna, including the routes of mounted
+// subrouters, sorted by pattern and method.
+func (z *Yuna) RouteInventory() []RouteInfo {
+	return routeInventory(z.router)
+}
+
+func routeInventory(routes chi.Routes) []RouteInfo {
+	inventory := make([]RouteInfo, 0)
+	walkRoutes(routes, "", nil, func(method, pattern string, handler http.Handler, middlewares []func(http.Handler) http.Handler) {
+		info := RouteInfo{
+			Method:      method,
+			Pattern:     pattern,
+			Middlewares: make([]string, 0, len(middlewares)),
+		}
+
+		// Middleware registered on the route when the Handler was registered is hidden inside the
+		// endpoint, and runs after the middleware chi knows about.
+		if ep, ok := handler.(*endpoint); ok {
+			for _, mw := range ep.middlewares {
+				middlewares = append(middlewares, mw)
+			}
+		}
+
+		for _, mw := range middlewares {
+			info.Middlewares = append(info.Middlewares, middlewareName(mw))
+			if guard, ok := inspectAuthGuard(mw); ok {
+				info.Protected = true
+				if guard.role != "" {
+					info.Roles = append(info.Roles, guard.role)
+				}
+			}
+		}
+
+		inventory = append(inventory, info)
+	})
+
+	sort.Slice(inventory, func(i, j int) bool {
+		if inventory[i].Pattern == inventory[j].Pattern {
+			return inventory[i].Method < inventory[j].Method
+		}
+		return inventory[i].Pattern < inventory[j].Pattern
+	})
+	return inventory
+}
+
+// walkRoutes walks the route tree invoking fn for every method and pattern. It is similar to chi.Walk
+// but also includes the middleware applied with With to subrouters registered with Route or Mount,
+// which chi.Walk omits.
+func walkRoutes(routes chi.Routes, parent string, parentMws []func(http.Handler) http.Handler,
+	fn func(method, pattern string, handler http.Handler, middlewares []func(http.Handler) http.Handler)) {
+
+	for _, route := range routes.Routes() {
+		mws := make([]func(http.Handler) http.Handler, 0, len(parentMws)+len(routes.Middlewares()))
+		mws = append(mws, parentMws...)
+		mws = append(mws, routes.Middlewares()...)
+
+		if route.SubRoutes != nil {
+			for _, handler := range route.Handlers {
+				if chain, ok := handler.(*chi.ChainHandler); ok {
+					mws = append(mws, chain.Middlewares...)
+					break
+				}
+			}
+			walkRoutes(route.SubRoutes, parent+strings.TrimSuffix(route.Pattern, "/*"), mws, fn)
+			continue
+		}
+
+		for method, handler := range route.Handlers {
+			if method == "*" {
+				continue
+			}
+
+			routeMws := mws
+			if chain, ok := handler.(*chi.ChainHandler); ok {
+				handler = chain.Endpoint
+				routeMws = append(routeMws[:len(routeMws):len(routeMws)], chain.Middlewares...)
+			}
+
+			pattern := strings.ReplaceAll(parent+route.Pattern, "/*/", "/")
+			fn(method, pattern, handler, routeMws[:len(routeMws):len(routeMws)])
+		}
+	}
+}
+
+var closureSuffix = regexp.MustCompile(`(\.func\d+|\.\d+)+$|-fm$`)
+
+// middlewareName returns a human-readable name for the middleware derived from the function that
+// created it, for example "yuna.RequireRole" or "middleware.Trace".
+func middlewareName(mw any) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(mw).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+	name := closureSuffix.ReplaceAllString(fn.Name(), "")
+	if idx := strings.LastIndex(name, "/"); idx >= 0 {
+		name = name[idx+1:]
+	}
+	return name
+}
+
+// inspectAuthGuard determines if the middleware is the Authenticated or RequireRole middleware. Only
+// Yuna's own middleware is applied to a placeholder handler to inspect it, as third-party middleware
+// may have side effects.
+func inspectAuthGuard(mw func(http.Handler) http.Handler) (*authGuard, bool) {
+	name := middlewareName(mw)
+	if name != "yuna.Authenticated" && name != "yuna.RequireRole" {
+		return nil, false
+	}
+	guard, ok := mw(http.NotFoundHandler()).(*authGuard)
+	return guard, ok
+}
+
+// routesHandler serves the route inventory as JSON, or as a plain-text table if the format query
+// parameter is "text" or the client prefers text/plain.
+func routesHandler(routes chi.Routes) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		inventory := routeInventory(routes)
+
+		w.Header().Set(HeaderCacheControl, "no-cache, no-store, must-revalidate")
+
+		format := r.URL.Query().Get("format")
+		if format == "text" || (format == "" && strings.HasPrefix(r.Header.Get(HeaderAccept), MIMETextPlain)) {
+			w.Header().Set(HeaderContentType, MIMETextPlainCharsetUTF8)
+			w.WriteHeader(http.StatusOK)
+
+			tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(tw, "METHOD\tPATTERN\tPROTECTED\tROLES\tMIDDLEWARES")
+			for _, route := range inventory {
+				_, _ = fmt.Fprintf(tw, "%s\t%s\t%t\t%s\t%s\n",
+					route.Method,
+					route.Pattern,
+					route.Protected,
+					strings.Join(route.Roles, ","),
+					strings.Join(route.Middlewares, ","))
+			}
+			_ = tw.Flush()
+			return
+		}
+
+		w.Header().Set(HeaderContentType, MIMEApplicationJSON)
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"routes": inventory,
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package yuna
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+
+	"github.com/jkratz55/yuna/internal"
+)
+
+// settings are the settings of a Yuna instance that Request and the responders need while handling
+// a request. They are made available through the request context, since responders only have
+// access to the http.Request.
+type settings struct {
+	validator              Validator
+	codecs                 *codecRegistry
+	metrics                *responderMetrics
+	redirectHosts          []string
+	authenticatorDecorator func(HttpAuthenticator) HttpAuthenticator
+}
+
+// defaultSettings are used when a request wasn't routed through Yuna, for example when a Handler is
+// tested directly with httptest.
+var defaultSettings = &settings{
+	validator: NewValidator(),
+	codecs:    newCodecRegistry(defaultCodecs()...),
+	metrics:   newResponderMetrics(otel.GetMeterProvider()),
+}
+
+func newSettings(conf *config) *settings {
+	return &settings{
+		validator:              conf.validator,
+		codecs:                 newCodecRegistry(append(defaultCodecs(), conf.codecs...)...),
+		metrics:                newResponderMetrics(conf.meterProvider),
+		redirectHosts:          conf.redirectHosts,
+		authenticatorDecorator: conf.authenticatorDecorator,
+	}
+}
+
+// authenticator returns the HttpAuthenticator used by the Authenticate middleware, which is
+// authenticator wrapped by the decorator set with WithAuthenticatorDecorator.
+func (s *settings) authenticator(authenticator HttpAuthenticator) HttpAuthenticator {
+	if s.authenticatorDecorator == nil {
+		return authenticator
+	}
+	return s.authenticatorDecorator(authenticator)
+}
+
+// withSettings returns an HTTP middleware that stores the settings in the request context.
+func withSettings(s *settings) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), internal.ContextKeySettings, s)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func settingsFromCtx(ctx context.Context) *settings {
+	if s, ok := ctx.Value(internal.ContextKeySettings).(*settings); ok {
+		return s
+	}
+	return defaultSettings
+}
@@ -0,0 +1,219 @@
+package yuna
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+)
+
+// defaultHeartbeat is the interval heartbeats are sent on a Server-Sent Events stream unless
+// changed with SSEResponder.Heartbeat.
+const defaultHeartbeat = 15 * time.Second
+
+// An Event is a Server-Sent Event sent to the client by SSEResponder.
+type Event struct {
+	// ID is the event ID, which the client sends back in the Last-Event-ID header when it
+	// reconnects. Optional.
+	ID string
+
+	// Event is the event type, which selects the listener on the client's EventSource. If empty the
+	// client dispatches a "message" event.
+	Event string
+
+	// Data is the payload of the event. Strings and byte slices are sent as is, spanning multiple
+	// lines if they contain newlines, all other values are encoded as JSON.
+	Data any
+
+	// Retry tells the client how long to wait before reconnecting if the connection is lost.
+	// Ignored if zero.
+	Retry time.Duration
+}
+
+// SSEResponder is a Responder streaming Server-Sent Events to the client until the source of events
+// is exhausted or the client disconnects. While idle, heartbeat comments are sent periodically so
+// proxies don't close the connection.
+//
+// SSEResponder disables the write timeout of the server for the request, since the stream is
+// expected to outlive it.
+type SSEResponder struct {
+	source    func(ctx context.Context) (<-chan Event, func())
+	heartbeat time.Duration
+}
+
+// SSE returns a Responder streaming the events received from the channel as Server-Sent Events.
+// The stream ends when the channel is closed or the client disconnects.
+//
+// Streams can be resumed by sending the events following the ID in the Last-Event-ID header, see
+// Request.LastEventID. Hub.Subscribe does this for events published to a Hub:
+//
+//	router.Get("/orders/events", func(r *yuna.Request) yuna.Responder {
+//		return yuna.SSE(hub.Subscribe(r.Context(), r.LastEventID(), "orders"))
+//	})
+func SSE(events <-chan Event) *SSEResponder {
+	return &SSEResponder{
+		source: func(context.Context) (<-chan Event, func()) {
+			return events, func() {}
+		},
+		heartbeat: defaultHeartbeat,
+	}
+}
+
+// SSESeq returns a Responder streaming the events yielded by seq as Server-Sent Events. The stream
+// ends when seq returns or the client disconnects. Since seq is iterated on another goroutine, seq
+// should stop when ctx is done if it blocks waiting for events.
+func SSESeq(seq iter.Seq[Event]) *SSEResponder {
+	return &SSEResponder{
+		source: func(ctx context.Context) (<-chan Event, func()) {
+			ctx, cancel := context.WithCancel(ctx)
+			events := make(chan Event)
+			go func() {
+				defer close(events)
+				for ev := range seq {
+					select {
+					case events <- ev:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+			return events, cancel
+		},
+		heartbeat: defaultHeartbeat,
+	}
+}
+
+// Heartbeat sets the interval heartbeat comments are sent on the stream. The default is 15
+// seconds. An interval of zero disables heartbeats.
+func (s *SSEResponder) Heartbeat(interval time.Duration) *SSEResponder {
+	s.heartbeat = interval
+	return s
+}
+
+func (s *SSEResponder) Respond(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	settings := settingsFromCtx(ctx)
+	rc := http.NewResponseController(w)
+
+	// The stream is expected to outlive the write timeout of the server. Not all ResponseWriters
+	// support deadlines, in which case there is nothing to disable.
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set(HeaderContentType, MIMETextEventStream)
+	w.Header().Set(HeaderCacheControl, "no-cache")
+	// Disables response buffering by nginx, which would otherwise hold back events.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return fmt.Errorf("sse: flush: %w", err)
+	}
+
+	attrs := metric.WithAttributes(routeAttr(r))
+	settings.metrics.sseStreams.Add(ctx, 1, attrs)
+	defer settings.metrics.sseStreams.Add(context.WithoutCancel(ctx), -1, attrs)
+
+	codec := jsonCodec(r)
+	events, stop := s.source(ctx)
+	defer stop()
+
+	var heartbeat <-chan time.Time
+	if s.heartbeat > 0 {
+		ticker := time.NewTicker(s.heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	var buf bytes.Buffer
+	for {
+		buf.Reset()
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := writeEvent(&buf, ev, codec); err != nil {
+				return fmt.Errorf("sse: encode event: %w", err)
+			}
+			settings.metrics.sseEvents.Add(ctx, 1, attrs)
+		case <-heartbeat:
+			buf.WriteString(": heartbeat\n\n")
+		}
+
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			// The client went away, which is the normal way for a stream to end.
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("sse: write: %w", err)
+		}
+		if err := rc.Flush(); err != nil {
+			return fmt.Errorf("sse: flush: %w", err)
+		}
+	}
+}
+
+// writeEvent writes the event in the text/event-stream format.
+func writeEvent(buf *bytes.Buffer, ev Event, codec Codec) error {
+	if ev.ID != "" {
+		buf.WriteString("id: ")
+		buf.WriteString(sseField(ev.ID))
+		buf.WriteByte('\n')
+	}
+	if ev.Event != "" {
+		buf.WriteString("event: ")
+		buf.WriteString(sseField(ev.Event))
+		buf.WriteByte('\n')
+	}
+	if ev.Retry > 0 {
+		buf.WriteString("retry: ")
+		buf.WriteString(strconv.FormatInt(ev.Retry.Milliseconds(), 10))
+		buf.WriteByte('\n')
+	}
+
+	var data string
+	switch val := ev.Data.(type) {
+	case nil:
+	case string:
+		data = val
+	case []byte:
+		data = string(val)
+	default:
+		var encoded bytes.Buffer
+		if err := codec.Encode(&encoded, val); err != nil {
+			return err
+		}
+		data = strings.TrimRight(encoded.String(), "\n")
+	}
+
+	// Each line of the data is sent as a separate data field, which the client joins with newlines.
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\r", "\n")
+	for line := range strings.SplitSeq(data, "\n") {
+		buf.WriteString("data: ")
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	return nil
+}
+
+// sseField removes line breaks from the value of a single line field, which would otherwise end the
+// field early and allow injecting fields into the stream.
+func sseField(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// LastEventID returns the ID of the last Server-Sent Event received by the client, sent in the
+// Last-Event-ID header when an EventSource reconnects, so the stream can resume after it. Returns an
+// empty string for new streams.
+func (r *Request) LastEventID() string {
+	return r.raw.Header.Get(HeaderLastEventID)
+}
@@ -0,0 +1,340 @@
+package yuna
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/jkratz55/yuna/log"
+)
+
+// A StaticOption configures how files are served by Mux.Static and Yuna.Static.
+type StaticOption func(c *staticConfig)
+
+type staticConfig struct {
+	browse       bool
+	spa          bool
+	index        string
+	immutable    func(name string) bool
+	cacheControl string
+}
+
+// StaticBrowse enables listing the files of directories that don't contain an index file. By
+// default, requests for such directories receive a 404 Not Found problem.
+func StaticBrowse() StaticOption {
+	return func(c *staticConfig) {
+		c.browse = true
+	}
+}
+
+// StaticSPA serves the index file of the root directory for requests that don't match a file, so a
+// single-page application can handle the path with client side routing. Requests for paths with a
+// file extension, such as /app.js, still receive a 404 Not Found problem if the file doesn't
+// exist, since they are requests for assets rather than pages.
+func StaticSPA() StaticOption {
+	return func(c *staticConfig) {
+		c.spa = true
+	}
+}
+
+// StaticIndex sets the name of the file served for requests for a directory. The default is
+// index.html.
+func StaticIndex(name string) StaticOption {
+	if name == "" || strings.Contains(name, "/") {
+		panic("static index must be a file name")
+	}
+	return func(c *staticConfig) {
+		c.index = name
+	}
+}
+
+// StaticImmutable sets the function reporting whether the content of a file never changes because
+// its name contains a hash of its content, such as app-3f2a9c1b.js. Immutable files are sent with
+// the Cache-Control header "public, max-age=31536000, immutable" so browsers never revalidate
+// them. By default, file names are immutable if the extension is preceded by a segment of at least
+// 8 letters and digits, including at least one digit, separated by '.', '-', or '_', as produced by
+// webpack, Vite, and esbuild.
+func StaticImmutable(fn func(name string) bool) StaticOption {
+	if fn == nil {
+		panic("static immutable func cannot be nil")
+	}
+	return func(c *staticConfig) {
+		c.immutable = fn
+	}
+}
+
+// StaticCacheControl sets the Cache-Control header sent with files that aren't immutable. The
+// default is "no-cache", which allows caching but requires revalidation using the ETag or
+// Last-Modified date. Index files are always sent with "no-cache" so new deployments are picked up.
+func StaticCacheControl(value string) StaticOption {
+	return func(c *staticConfig) {
+		c.cacheControl = value
+	}
+}
+
+// immutableCacheControl is the Cache-Control header sent with files whose name contains a hash.
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// precompressed are the content codings of precompressed variants of files, in order of preference,
+// and the extension of their file names.
+var precompressed = []struct {
+	coding string
+	ext    string
+}{
+	{coding: "br", ext: ".br"},
+	{coding: "gzip", ext: ".gz"},
+}
+
+// static registers the routes serving the files of fsys under prefix with the router.
+func static(router chi.Router, prefix string, fsys fs.FS, opts ...StaticOption) {
+	if fsys == nil {
+		panic("static file system cannot be nil")
+	}
+
+	h := &staticHandler{
+		fsys: fsys,
+		conf: staticConfig{
+			browse:       false,
+			spa:          false,
+			index:        "index.html",
+			immutable:    isHashedName,
+			cacheControl: "no-cache",
+		},
+	}
+	for _, opt := range opts {
+		opt(&h.conf)
+	}
+
+	// The route pattern, rather than the path of the file, is reported as the http.route of the
+	// requests, which keeps the cardinality of the metrics bounded.
+	prefix = strings.TrimSuffix("/"+strings.Trim(prefix, "/"), "/")
+	handler := wrap(HandlerFunc(h.serve))
+	router.Method(http.MethodGet, prefix+"/*", handler)
+	router.Method(http.MethodHead, prefix+"/*", handler)
+
+	if prefix != "" {
+		redirect := wrap(HandlerFunc(func(*Request) Responder {
+			return ResponderFunc(redirectToDir)
+		}))
+		router.Method(http.MethodGet, prefix, redirect)
+		router.Method(http.MethodHead, prefix, redirect)
+	}
+}
+
+type staticHandler struct {
+	fsys  fs.FS
+	conf  staticConfig
+	etags sync.Map
+}
+
+func (h *staticHandler) serve(r *Request) Responder {
+	name := path.Clean("/" + chi.URLParam(r.raw, "*"))
+	name = strings.TrimPrefix(name, "/")
+	if name == "" {
+		name = "."
+	}
+	if !fs.ValidPath(name) || isHiddenPath(name) {
+		return NotFound()
+	}
+
+	info, err := fs.Stat(h.fsys, name)
+	switch {
+	case err == nil && info.IsDir():
+		if !strings.HasSuffix(r.raw.URL.Path, "/") {
+			return ResponderFunc(redirectToDir)
+		}
+		index := path.Join(name, h.conf.index)
+		if info, err := fs.Stat(h.fsys, index); err == nil && !info.IsDir() {
+			return h.file(r, index, info)
+		}
+		if h.conf.browse {
+			return h.list(name)
+		}
+	case err == nil:
+		return h.file(r, name, info)
+	case !errors.Is(err, fs.ErrNotExist):
+		return InternalServerError(fmt.Errorf("static: stat %s: %w", name, err))
+	}
+
+	if h.conf.spa && path.Ext(name) == "" {
+		if info, err := fs.Stat(h.fsys, h.conf.index); err == nil && !info.IsDir() {
+			return h.file(r, h.conf.index, info)
+		}
+	}
+	return NotFound()
+}
+
+// file returns the Responder sending the file, or its precompressed variant if the client accepts
+// it.
+func (h *staticHandler) file(r *Request, name string, info fs.FileInfo) Responder {
+	acceptEncoding := r.raw.Header.Get(HeaderAcceptEncoding)
+
+	var (
+		f        fs.File
+		coding   string
+		variant  = name
+		variants bool
+	)
+	for _, pc := range precompressed {
+		vinfo, err := fs.Stat(h.fsys, name+pc.ext)
+		if err != nil || vinfo.IsDir() {
+			continue
+		}
+		variants = true
+		if f != nil || !acceptsEncoding(acceptEncoding, pc.coding) {
+			continue
+		}
+		if vf, err := h.fsys.Open(name + pc.ext); err == nil {
+			f, coding, variant, info = vf, pc.coding, name+pc.ext, vinfo
+		}
+	}
+	if f == nil {
+		var err error
+		if f, err = h.fsys.Open(name); err != nil {
+			return InternalServerError(fmt.Errorf("static: open %s: %w", name, err))
+		}
+	}
+
+	resp := fileResponder(f, name)
+	if variants {
+		resp.Header(HeaderVary, HeaderAcceptEncoding)
+	}
+	if coding != "" {
+		// The Content-Type cannot be sniffed from compressed content.
+		ct := mime.TypeByExtension(path.Ext(name))
+		if ct == "" {
+			ct = MIMEOctetStream
+		}
+		resp.ContentType(ct).Header(HeaderContentEncoding, coding)
+	}
+
+	switch {
+	case path.Base(name) == h.conf.index:
+		resp.Header(HeaderCacheControl, "no-cache")
+	case h.conf.immutable(path.Base(name)):
+		resp.Header(HeaderCacheControl, immutableCacheControl)
+	case h.conf.cacheControl != "":
+		resp.Header(HeaderCacheControl, h.conf.cacheControl)
+	}
+
+	if etag, err := h.etag(variant, info); err == nil {
+		resp.ETag(etag)
+	} else {
+		log.LoggerFromCtx(r.Context()).Warn("Failed to compute ETag of static file",
+			log.String("file", variant), log.Error(err))
+	}
+	return resp
+}
+
+// list returns a Responder listing the files of the directory using http.FileServerFS.
+func (h *staticHandler) list(dir string) Responder {
+	return ResponderFunc(func(w http.ResponseWriter, r *http.Request) error {
+		r = r.Clone(r.Context())
+		r.URL.Path = "/"
+		if dir != "." {
+			r.URL.Path = "/" + dir + "/"
+		}
+		http.FileServerFS(h.fsys).ServeHTTP(w, r)
+		return nil
+	})
+}
+
+// etagKey identifies the version of a file an ETag was computed for.
+type etagKey struct {
+	name    string
+	size    int64
+	modtime time.Time
+}
+
+// etag returns a strong entity tag derived from the content of the file. Files embedded with
+// embed.FS don't have a modification time, so the ETag is the only validator for conditional
+// requests. ETags are cached until the size or modification time of the file changes.
+func (h *staticHandler) etag(name string, info fs.FileInfo) (string, error) {
+	key := etagKey{name: name, size: info.Size(), modtime: info.ModTime()}
+	if etag, ok := h.etags.Load(key); ok {
+		return etag.(string), nil
+	}
+
+	f, err := h.fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	etag := hashETag(hash.Sum(nil))
+	h.etags.Store(key, etag)
+	return etag, nil
+}
+
+// redirectToDir redirects requests for a directory to the path with a trailing slash, so relative
+// links in the index file resolve correctly.
+func redirectToDir(w http.ResponseWriter, r *http.Request) error {
+	localRedirect(w, r, path.Base(r.URL.Path)+"/")
+	return nil
+}
+
+// localRedirect redirects to a path relative to the current one, which cannot be abused to
+// redirect to another host.
+func localRedirect(w http.ResponseWriter, r *http.Request, target string) {
+	if q := r.URL.RawQuery; q != "" {
+		target += "?" + q
+	}
+	w.Header().Set(HeaderLocation, target)
+	w.WriteHeader(http.StatusMovedPermanently)
+}
+
+// isHiddenPath reports whether any element of the path starts with a dot, such as .git or .env.
+// Hidden files are never served. The .well-known directory isn't hidden, since it holds the files
+// clients expect to find at well-known locations, see RFC 8615.
+func isHiddenPath(name string) bool {
+	for elem := range strings.SplitSeq(name, "/") {
+		if strings.HasPrefix(elem, ".") && elem != "." && elem != ".well-known" {
+			return true
+		}
+	}
+	return false
+}
+
+// isHashedName reports whether the file name contains a content hash preceding the extension, such
+// as app.3f2a9c1b.js or index-BxYz12Ab.css.
+func isHashedName(name string) bool {
+	ext := path.Ext(name)
+	if ext == "" {
+		return false
+	}
+	stem := strings.TrimSuffix(name, ext)
+	i := strings.LastIndexAny(stem, ".-_")
+	if i < 0 {
+		return false
+	}
+	hash := stem[i+1:]
+	if len(hash) < 8 {
+		return false
+	}
+
+	digits := false
+	for _, c := range hash {
+		switch {
+		case c >= '0' && c <= '9':
+			digits = true
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		default:
+			return false
+		}
+	}
+	return digits
+}
@@ -0,0 +1,500 @@
+package yuna
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StreamErrorTrailer is the HTTP trailer a StreamResponder uses to report an error that occurred
+// after the status code and some of the records were already sent to the client.
+const StreamErrorTrailer = "X-Stream-Error"
+
+// Defaults for how often a StreamResponder flushes records to the client.
+const (
+	defaultFlushEvery    = 100
+	defaultFlushInterval = time.Second
+)
+
+// StreamResponder is a Responder streaming records to the client as they are produced by an
+// iterator, without holding them all in memory. It is created by NDJSON, JSONArray, or CSV.
+//
+// Records are flushed to the client every 100 records or every second, whichever comes first,
+// which can be changed with FlushEvery and FlushInterval. The stream stops when the iterator is
+// exhausted or the client disconnects.
+//
+// Since the status code has already been sent, an error returned by the iterator part way through
+// the stream is reported in the X-Stream-Error trailer, and for NDJSON and JSONArray as a final
+// record of the form {"error": <problem details>}. If the error is or wraps a *ProblemDetails it is
+// reported as is, otherwise it is logged and reported as an Internal Server Error.
+type StreamResponder struct {
+	contentType   string
+	header        http.Header
+	flushEvery    int
+	flushInterval time.Duration
+	format        func(r *http.Request) streamFormat
+}
+
+// streamFormat writes the records of a stream in a specific format. A new streamFormat is created
+// for every response.
+type streamFormat interface {
+	// records iterates the records, writing each to w and calling flush after each record. It
+	// returns the error returned by the iterator or from encoding a record.
+	records(ctx context.Context, w io.Writer, flush func()) error
+
+	// end writes anything following the records, including the error record if problem isn't nil.
+	end(w io.Writer, problem *ProblemDetails)
+}
+
+// Infallible adapts an iter.Seq into the iter.Seq2 accepted by NDJSON, JSONArray, and CSV for
+// iterators that cannot fail:
+//
+//	return yuna.NDJSON(yuna.Infallible(slices.Values(orders)))
+func Infallible[T any](seq iter.Seq[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for v := range seq {
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
+
+// NDJSON returns a Responder streaming the values yielded by seq as newline delimited JSON
+// (application/x-ndjson), one JSON value per line.
+func NDJSON[T any](seq iter.Seq2[T, error]) *StreamResponder {
+	return newStreamResponder(MIMEApplicationNDJSON, func(r *http.Request) streamFormat {
+		return &jsonStream[T]{seq: seq, codec: jsonCodec(r), delimited: true}
+	})
+}
+
+// JSONArray returns a Responder streaming the values yielded by seq as the elements of a JSON array
+// (application/json).
+func JSONArray[T any](seq iter.Seq2[T, error]) *StreamResponder {
+	return newStreamResponder(MIMEApplicationJSON, func(r *http.Request) streamFormat {
+		return &jsonStream[T]{seq: seq, codec: jsonCodec(r), delimited: false}
+	})
+}
+
+// CSV returns a Responder streaming the values yielded by seq as rows of comma separated values
+// (text/csv).
+//
+// If T is a struct, or a pointer to a struct, the first row is a header with a column for each
+// exported field, named using the csv struct tag or the name of the field. Fields tagged with
+// `csv:"-"` are omitted and embedded structs are flattened. Values are formatted using
+// encoding.TextMarshaler or fmt.Stringer if implemented, nil pointers as empty values. If T is a
+// []string each value is written as a row as is, without a header.
+//
+// Errors are only reported in the X-Stream-Error trailer, since an error record would not match the
+// columns of the other rows.
+func CSV[T any](seq iter.Seq2[T, error]) *StreamResponder {
+	columns := csvColumns(reflect.TypeFor[T]())
+	return newStreamResponder(MIMETextCSV, func(r *http.Request) streamFormat {
+		return &csvStream[T]{seq: seq, columns: columns}
+	})
+}
+
+func newStreamResponder(contentType string, format func(r *http.Request) streamFormat) *StreamResponder {
+	return &StreamResponder{
+		contentType:   contentType,
+		header:        http.Header{},
+		flushEvery:    defaultFlushEvery,
+		flushInterval: defaultFlushInterval,
+		format:        format,
+	}
+}
+
+// Header adds a header to the response, for example a Content-Disposition header so the browser
+// saves the stream as a file.
+func (s *StreamResponder) Header(key string, values ...string) *StreamResponder {
+	for _, value := range values {
+		s.header.Add(key, value)
+	}
+	return s
+}
+
+// FlushEvery sets the number of records written before they are flushed to the client. The default
+// is 100.
+func (s *StreamResponder) FlushEvery(n int) *StreamResponder {
+	s.flushEvery = max(n, 1)
+	return s
+}
+
+// FlushInterval sets the maximum time records are buffered before they are flushed to the client.
+// The default is one second.
+func (s *StreamResponder) FlushInterval(interval time.Duration) *StreamResponder {
+	s.flushInterval = interval
+	return s
+}
+
+func (s *StreamResponder) Respond(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	rc := http.NewResponseController(w)
+
+	for key, values := range s.header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.Header().Set(HeaderContentType, contentType(s.contentType))
+	w.Header().Add(HeaderTrailer, StreamErrorTrailer)
+	w.WriteHeader(http.StatusOK)
+
+	sw := &streamWriter{
+		w:             w,
+		rc:            rc,
+		flushEvery:    s.flushEvery,
+		flushInterval: s.flushInterval,
+	}
+	defer sw.stop()
+
+	format := s.format(r)
+	err := format.records(ctx, sw, sw.recorded)
+	sw.stop()
+	if sw.err != nil || ctx.Err() != nil {
+		// The client went away, which isn't an error worth reporting.
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("stream: write: %w", sw.err)
+	}
+
+	var problem *ProblemDetails
+	if err != nil {
+		problem = problemFromError(ctx, err)
+		problem.populate(r)
+		w.Header().Set(StreamErrorTrailer, streamErrorTrailer(problem))
+	}
+	format.end(sw, problem)
+	if sw.err != nil {
+		return fmt.Errorf("stream: write: %w", sw.err)
+	}
+	return nil
+}
+
+// streamErrorTrailer returns the value of the X-Stream-Error trailer for the problem.
+func streamErrorTrailer(problem *ProblemDetails) string {
+	msg := problem.Detail
+	if msg == "" {
+		msg = problem.Title
+	}
+	return strings.Join(strings.Fields(msg), " ")
+}
+
+// streamWriter writes the records of a stream to the client, flushing them after every flushEvery
+// records, or once the oldest record that wasn't flushed has been buffered for flushInterval. The
+// interval is driven by a timer, so records are flushed even if the iterator is slow to produce the
+// next one. Writes and flushes are serialized by mu since the timer flushes from its own goroutine.
+//
+// Errors writing to the client are sticky, once the client is gone there is no point encoding the
+// remaining records.
+type streamWriter struct {
+	mu            sync.Mutex
+	w             io.Writer
+	rc            *http.ResponseController
+	err           error
+	flushEvery    int
+	flushInterval time.Duration
+	pending       int
+	timer         *time.Timer
+	stopped       bool
+}
+
+func (sw *streamWriter) Write(p []byte) (int, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	if sw.err != nil {
+		return 0, sw.err
+	}
+	n, err := sw.w.Write(p)
+	if err != nil {
+		sw.err = err
+	}
+	return n, err
+}
+
+// recorded is called after each record is written, flushing the records once flushEvery are
+// pending and otherwise starting the timer flushing them after flushInterval.
+func (sw *streamWriter) recorded() {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	sw.pending++
+	switch {
+	case sw.pending >= sw.flushEvery || sw.flushInterval <= 0:
+		sw.flush()
+	case sw.timer == nil:
+		sw.timer = time.AfterFunc(sw.flushInterval, sw.flushPending)
+	}
+}
+
+// flushPending flushes the pending records when the flush interval elapses.
+func (sw *streamWriter) flushPending() {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	sw.timer = nil
+	if sw.stopped || sw.pending == 0 {
+		return
+	}
+	sw.flush()
+}
+
+// flush flushes the records to the client, sw.mu must be held.
+func (sw *streamWriter) flush() {
+	if sw.timer != nil {
+		sw.timer.Stop()
+		sw.timer = nil
+	}
+	sw.pending = 0
+	if sw.err != nil {
+		return
+	}
+	if err := sw.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		sw.err = err
+	}
+}
+
+// stop stops flushing records on a timer, the remainder of the response is flushed by the server
+// once the handler returns.
+func (sw *streamWriter) stop() {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	sw.stopped = true
+	if sw.timer != nil {
+		sw.timer.Stop()
+		sw.timer = nil
+	}
+}
+
+// jsonCodec returns the Codec registered for application/json, which may have been replaced with
+// WithCodecs.
+func jsonCodec(r *http.Request) Codec {
+	if codec, ok := settingsFromCtx(r.Context()).codecs.lookup(MIMEApplicationJSON); ok {
+		return codec
+	}
+	return JSONCodec{}
+}
+
+// jsonStream writes records as newline delimited JSON, or as the elements of a JSON array.
+type jsonStream[T any] struct {
+	seq       iter.Seq2[T, error]
+	codec     Codec
+	delimited bool
+	count     int
+}
+
+func (s *jsonStream[T]) records(ctx context.Context, w io.Writer, flush func()) error {
+	if !s.delimited {
+		_, _ = io.WriteString(w, "[")
+	}
+
+	var buf bytes.Buffer
+	for v, err := range s.seq {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		buf.Reset()
+		if !s.delimited && s.count > 0 {
+			buf.WriteByte(',')
+		}
+		if err := s.codec.Encode(&buf, v); err != nil {
+			return fmt.Errorf("stream: encode record: %w", err)
+		}
+		if s.delimited && !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+			buf.WriteByte('\n')
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return nil
+		}
+		s.count++
+		flush()
+	}
+	return nil
+}
+
+func (s *jsonStream[T]) end(w io.Writer, problem *ProblemDetails) {
+	if problem != nil {
+		if !s.delimited && s.count > 0 {
+			_, _ = io.WriteString(w, ",")
+		}
+		_ = s.codec.Encode(w, map[string]any{"error": problem})
+	}
+	if !s.delimited {
+		_, _ = io.WriteString(w, "]\n")
+	}
+}
+
+// csvStream writes records as rows of comma separated values.
+type csvStream[T any] struct {
+	seq     iter.Seq2[T, error]
+	columns []csvColumn
+}
+
+func (s *csvStream[T]) records(ctx context.Context, w io.Writer, flush func()) error {
+	cw := csv.NewWriter(w)
+	if s.columns != nil {
+		header := make([]string, len(s.columns))
+		for i, col := range s.columns {
+			header[i] = col.name
+		}
+		_ = cw.Write(header)
+	}
+
+	for v, err := range s.seq {
+		if err != nil {
+			cw.Flush()
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		row, err := s.row(v)
+		if err != nil {
+			cw.Flush()
+			return fmt.Errorf("stream: encode record: %w", err)
+		}
+		_ = cw.Write(row)
+		cw.Flush()
+		if cw.Error() != nil {
+			return nil
+		}
+		flush()
+	}
+	cw.Flush()
+	return nil
+}
+
+func (s *csvStream[T]) row(v T) ([]string, error) {
+	if s.columns == nil {
+		if row, ok := any(v).([]string); ok {
+			return row, nil
+		}
+		return nil, fmt.Errorf("cannot encode %T as csv", v)
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return make([]string, len(s.columns)), nil
+		}
+		rv = rv.Elem()
+	}
+
+	row := make([]string, len(s.columns))
+	for i, col := range s.columns {
+		fv, err := rv.FieldByIndexErr(col.index)
+		if err != nil {
+			// A nil embedded pointer, the field is left empty.
+			continue
+		}
+		val, err := csvValue(fv)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", col.name, err)
+		}
+		row[i] = val
+	}
+	return row, nil
+}
+
+func (s *csvStream[T]) end(io.Writer, *ProblemDetails) {}
+
+// csvColumn is a column of a CSV stream mapped to a struct field.
+type csvColumn struct {
+	name  string
+	index []int
+}
+
+// csvColumns returns the columns for the struct type t, or nil if t isn't a struct.
+func csvColumns(t reflect.Type) []csvColumn {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	columns := make([]csvColumn, 0, t.NumField())
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() {
+			continue
+		}
+		tag, hasTag := field.Tag.Lookup("csv")
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		// Embedded structs are flattened, their fields are visible on their own.
+		ft := field.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if field.Anonymous && ft.Kind() == reflect.Struct && !hasTag {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		columns = append(columns, csvColumn{name: name, index: field.Index})
+	}
+	return columns
+}
+
+// csvValue formats the value of a field for a CSV row.
+func csvValue(v reflect.Value) (string, error) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	if v.CanInterface() {
+		switch val := v.Interface().(type) {
+		case encoding.TextMarshaler:
+			text, err := val.MarshalText()
+			return string(text), err
+		case fmt.Stringer:
+			return val.String(), nil
+		}
+	}
+	if v.CanAddr() {
+		if tm, ok := v.Addr().Interface().(encoding.TextMarshaler); ok {
+			text, err := tm.MarshalText()
+			return string(text), err
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	default:
+		return fmt.Sprint(v.Interface()), nil
+	}
+}
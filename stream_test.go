@@ -0,0 +1,389 @@
+package yuna
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type streamRecord struct {
+	ID int `json:"id"`
+}
+
+// records returns an iterator yielding n records with the IDs 1 to n, followed by err if not nil.
+func records(n int, err error) iter.Seq2[streamRecord, error] {
+	return func(yield func(streamRecord, error) bool) {
+		for i := 1; i <= n; i++ {
+			if !yield(streamRecord{ID: i}, nil) {
+				return
+			}
+		}
+		if err != nil {
+			yield(streamRecord{}, err)
+		}
+	}
+}
+
+func respondStream(t *testing.T, s *StreamResponder) *http.Response {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/stream", nil)
+	if err := s.Respond(rec, req); err != nil {
+		t.Fatalf("Respond() error = %v", err)
+	}
+	return rec.Result()
+}
+
+func TestStreamNDJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		seq         iter.Seq2[streamRecord, error]
+		wantLines   []string
+		wantTrailer string
+	}{
+		{
+			name:      "records",
+			seq:       records(3, nil),
+			wantLines: []string{`{"id":1}`, `{"id":2}`, `{"id":3}`},
+		},
+		{
+			name:      "no records",
+			seq:       records(0, nil),
+			wantLines: nil,
+		},
+		{
+			name:        "problem after records",
+			seq:         records(2, BadRequest(nil).SetDetail("The cursor expired.")),
+			wantLines:   []string{`{"id":1}`, `{"id":2}`, "error"},
+			wantTrailer: "The cursor expired.",
+		},
+		{
+			name:        "error before any record",
+			seq:         records(0, errors.New("connection refused")),
+			wantLines:   []string{"error"},
+			wantTrailer: InternalServerError().Detail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := respondStream(t, NDJSON(tt.seq))
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("status = %d, want 200", resp.StatusCode)
+			}
+			if got := resp.Header.Get(HeaderContentType); !strings.HasPrefix(got, MIMEApplicationNDJSON) {
+				t.Errorf("Content-Type = %q", got)
+			}
+
+			var lines []string
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				lines = append(lines, scanner.Text())
+			}
+			if len(lines) != len(tt.wantLines) {
+				t.Fatalf("lines = %q, want %d lines", lines, len(tt.wantLines))
+			}
+			for i, want := range tt.wantLines {
+				if want == "error" {
+					assertErrorRecord(t, lines[i])
+					continue
+				}
+				if lines[i] != want {
+					t.Errorf("line %d = %s, want %s", i, lines[i], want)
+				}
+			}
+			if got := resp.Trailer.Get(StreamErrorTrailer); got != tt.wantTrailer {
+				t.Errorf("%s = %q, want %q", StreamErrorTrailer, got, tt.wantTrailer)
+			}
+		})
+	}
+}
+
+func TestStreamJSONArray(t *testing.T) {
+	tests := []struct {
+		name      string
+		seq       iter.Seq2[streamRecord, error]
+		wantIDs   []int
+		wantError bool
+	}{
+		{
+			name:    "records",
+			seq:     records(3, nil),
+			wantIDs: []int{1, 2, 3},
+		},
+		{
+			name:    "no records",
+			seq:     records(0, nil),
+			wantIDs: []int{},
+		},
+		{
+			name:      "error after records",
+			seq:       records(2, Conflict().SetDetail("The snapshot changed.")),
+			wantIDs:   []int{1, 2},
+			wantError: true,
+		},
+		{
+			name:      "error before any record",
+			seq:       records(0, Conflict().SetDetail("The snapshot changed.")),
+			wantIDs:   []int{},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := respondStream(t, JSONArray(tt.seq))
+
+			var elems []json.RawMessage
+			if err := json.NewDecoder(resp.Body).Decode(&elems); err != nil {
+				t.Fatalf("body isn't a JSON array: %v", err)
+			}
+			if tt.wantError {
+				if len(elems) == 0 {
+					t.Fatal("no error record")
+				}
+				assertErrorRecord(t, string(elems[len(elems)-1]))
+				elems = elems[:len(elems)-1]
+				if got := resp.Trailer.Get(StreamErrorTrailer); got != "The snapshot changed." {
+					t.Errorf("%s = %q", StreamErrorTrailer, got)
+				}
+			}
+
+			ids := make([]int, 0, len(elems))
+			for _, elem := range elems {
+				var rec streamRecord
+				if err := json.Unmarshal(elem, &rec); err != nil {
+					t.Fatal(err)
+				}
+				ids = append(ids, rec.ID)
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("ids = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func assertErrorRecord(t *testing.T, record string) {
+	t.Helper()
+
+	var rec struct {
+		Error *ProblemDetails `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(record), &rec); err != nil || rec.Error == nil {
+		t.Errorf("record %s isn't an error record: %v", record, err)
+		return
+	}
+	if rec.Error.StatusCode == 0 || rec.Error.Title == "" {
+		t.Errorf("error record %s has no status or title", record)
+	}
+}
+
+type csvAudit struct {
+	CreatedBy string `csv:"created_by"`
+}
+
+type csvItem struct {
+	ID int `csv:"id"`
+	csvAudit
+	*csvNote
+	Name     string
+	Price    float64   `csv:"price"`
+	Secret   string    `csv:"-"`
+	Shipped  *bool     `csv:"shipped,omitempty"`
+	Ordered  time.Time `csv:"ordered"`
+	internal string
+}
+
+type csvNote struct {
+	Note string `csv:"note"`
+}
+
+func TestStreamCSV(t *testing.T) {
+	shipped := true
+	ordered := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	items := []csvItem{
+		{ID: 1, csvAudit: csvAudit{CreatedBy: "ann"}, Name: "Shirt, blue", Price: 19.5, Secret: "x", Shipped: &shipped, Ordered: ordered},
+		{ID: 2, csvNote: &csvNote{Note: `says "hi"`}, Name: "Hat", Price: 5, internal: "y"},
+	}
+
+	seq := func(err error) iter.Seq2[csvItem, error] {
+		return func(yield func(csvItem, error) bool) {
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if err != nil {
+				yield(csvItem{}, err)
+			}
+		}
+	}
+
+	wantRows := "id,created_by,note,Name,price,shipped,ordered\n" +
+		"1,ann,,\"Shirt, blue\",19.5,true,2026-03-01T12:00:00Z\n" +
+		"2,,\"says \"\"hi\"\"\",Hat,5,,0001-01-01T00:00:00Z\n"
+
+	t.Run("records", func(t *testing.T) {
+		resp := respondStream(t, CSV(seq(nil)))
+		if got := resp.Header.Get(HeaderContentType); !strings.HasPrefix(got, MIMETextCSV) {
+			t.Errorf("Content-Type = %q", got)
+		}
+		if got := readBody(t, resp); got != wantRows {
+			t.Errorf("body =\n%s\nwant\n%s", got, wantRows)
+		}
+		if got := resp.Trailer.Get(StreamErrorTrailer); got != "" {
+			t.Errorf("%s = %q, want none", StreamErrorTrailer, got)
+		}
+	})
+
+	t.Run("error after records", func(t *testing.T) {
+		resp := respondStream(t, CSV(seq(ServiceUnavailable().SetDetail("The\nreplica is\tlagging."))))
+		// Errors are only reported in the trailer, an error record wouldn't match the columns.
+		if got := readBody(t, resp); got != wantRows {
+			t.Errorf("body =\n%s\nwant\n%s", got, wantRows)
+		}
+		if got := resp.Trailer.Get(StreamErrorTrailer); got != "The replica is lagging." {
+			t.Errorf("%s = %q", StreamErrorTrailer, got)
+		}
+	})
+
+	t.Run("string rows", func(t *testing.T) {
+		rows := Infallible(slices.Values([][]string{{"a", "b"}, {"c", "d,e"}}))
+		if got := readBody(t, respondStream(t, CSV(rows))); got != "a,b\nc,\"d,e\"\n" {
+			t.Errorf("body = %q", got)
+		}
+	})
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+
+	var sb strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		sb.WriteString(scanner.Text())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+func TestStreamFlush(t *testing.T) {
+	tests := []struct {
+		name     string
+		responds func(seq iter.Seq2[streamRecord, error]) *StreamResponder
+	}{
+		{
+			name: "interval",
+			responds: func(seq iter.Seq2[streamRecord, error]) *StreamResponder {
+				return NDJSON(seq).FlushEvery(100).FlushInterval(20 * time.Millisecond)
+			},
+		},
+		{
+			name: "every record",
+			responds: func(seq iter.Seq2[streamRecord, error]) *StreamResponder {
+				return NDJSON(seq).FlushEvery(1).FlushInterval(time.Hour)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The iterator blocks after the first record until the client received it, so the
+			// record is only received if it is flushed while the iterator is idle.
+			received := make(chan struct{})
+			seq := func(yield func(streamRecord, error) bool) {
+				if !yield(streamRecord{ID: 1}, nil) {
+					return
+				}
+				select {
+				case <-received:
+				case <-time.After(5 * time.Second):
+				}
+				yield(streamRecord{ID: 2}, nil)
+			}
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_ = tt.responds(seq).Respond(w, r)
+			}))
+			defer server.Close()
+
+			resp, err := http.Get(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			lines := make(chan string)
+			go func() {
+				scanner := bufio.NewScanner(resp.Body)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+				close(lines)
+			}()
+
+			select {
+			case line := <-lines:
+				if line != `{"id":1}` {
+					t.Errorf("first line = %s", line)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("first record wasn't flushed while the iterator was idle")
+			}
+			close(received)
+			if line := <-lines; line != `{"id":2}` {
+				t.Errorf("second line = %s", line)
+			}
+		})
+	}
+}
+
+func TestStreamStopsWhenClientDisconnects(t *testing.T) {
+	stopped := make(chan struct{})
+	responded := make(chan error, 1)
+	seq := func(yield func(streamRecord, error) bool) {
+		defer close(stopped)
+		for i := 0; ; i++ {
+			if !yield(streamRecord{ID: i}, nil) {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		responded <- NDJSON(seq).FlushEvery(1).Respond(w, r)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bufio.NewReader(resp.Body).ReadString('\n'); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	_ = resp.Body.Close()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("iterator wasn't stopped after the client disconnected")
+	}
+	if err := <-responded; err != nil {
+		t.Errorf("Respond() error = %v, want nil for a disconnected client", err)
+	}
+}
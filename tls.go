@@ -0,0 +1,234 @@
+package yuna
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/jkratz55/yuna/internal"
+	"github.com/jkratz55/yuna/log"
+)
+
+// A TLSOption configures the TLS settings for a server configured with WithTLS or WithOperationsTLS.
+type TLSOption func(s *tlsSettings)
+
+// TLSMinVersion sets the minimum TLS version the server accepts. The default is TLS 1.2.
+func TLSMinVersion(version uint16) TLSOption {
+	return func(s *tlsSettings) {
+		s.minVersion = version
+	}
+}
+
+// TLSCipherSuites sets the enabled TLS 1.0-1.2 cipher suites. TLS 1.3 cipher suites are not
+// configurable. If not set, the Go standard library defaults are used.
+func TLSCipherSuites(suites ...uint16) TLSOption {
+	return func(s *tlsSettings) {
+		s.cipherSuites = suites
+	}
+}
+
+// TLSNextProtos sets the application protocols advertised through ALPN, in order of preference.
+// The net/http package always advertises "http/1.1", and "h2" unless HTTP/2 is disabled.
+func TLSNextProtos(protos ...string) TLSOption {
+	return func(s *tlsSettings) {
+		s.nextProtos = protos
+	}
+}
+
+// TLSReloadInterval sets how often the certificate and key files are checked for changes. When the
+// files change the certificate is reloaded and used for new TLS handshakes without dropping existing
+// connections. The default is 1 minute. An interval of 0 disables reloading.
+func TLSReloadInterval(interval time.Duration) TLSOption {
+	return func(s *tlsSettings) {
+		s.reloadInterval = interval
+	}
+}
+
+type tlsSettings struct {
+	certFile       string
+	keyFile        string
+	minVersion     uint16
+	cipherSuites   []uint16
+	nextProtos     []string
+	reloadInterval time.Duration
+}
+
+func newTLSSettings(certFile, keyFile string, opts ...TLSOption) *tlsSettings {
+	settings := &tlsSettings{
+		certFile:       certFile,
+		keyFile:        keyFile,
+		minVersion:     tls.VersionTLS12,
+		cipherSuites:   nil,
+		nextProtos:     nil,
+		reloadInterval: time.Minute,
+	}
+	for _, opt := range opts {
+		opt(settings)
+	}
+	return settings
+}
+
+func (s *tlsSettings) tlsConfig(loader *certLoader) *tls.Config {
+	return &tls.Config{
+		MinVersion:     s.minVersion,
+		CipherSuites:   s.cipherSuites,
+		NextProtos:     s.nextProtos,
+		GetCertificate: loader.GetCertificate,
+	}
+}
+
+// certLoader loads a certificate and key pair from disk and keeps it up to date as the files are
+// rotated, for example by cert-manager. The current certificate is served through
+// tls.Config.GetCertificate so rotation doesn't require restarting the server.
+type certLoader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+	server   string
+	logger   *log.Logger
+
+	cert atomic.Pointer[tls.Certificate]
+
+	mu      sync.Mutex
+	certMod time.Time
+	keyMod  time.Time
+}
+
+func newCertLoader(server string, settings *tlsSettings, logger *log.Logger, mp metric.MeterProvider) *certLoader {
+	loader := &certLoader{
+		certFile: settings.certFile,
+		keyFile:  settings.keyFile,
+		interval: settings.reloadInterval,
+		server:   server,
+		logger:   logger,
+	}
+
+	if mp == nil {
+		mp = otel.GetMeterProvider()
+	}
+	meter := mp.Meter(internal.Scope, metric.WithInstrumentationVersion(internal.Version))
+	_, err := meter.Int64ObservableGauge("tls.server.certificate.expiration",
+		metric.WithDescription("Unix timestamp in seconds when the server certificate expires"),
+		metric.WithUnit("s"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			notAfter := loader.NotAfter()
+			if notAfter.IsZero() {
+				return nil
+			}
+			o.Observe(notAfter.Unix(), metric.WithAttributes(attribute.String("server", loader.server)))
+			return nil
+		}))
+	if err != nil {
+		panic(err)
+	}
+
+	return loader
+}
+
+// Load reads and parses the certificate and key files, replacing the current certificate.
+func (l *certLoader) Load() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	certMod, keyMod, err := l.modTimes()
+	if err != nil {
+		return err
+	}
+	return l.load(certMod, keyMod)
+}
+
+func (l *certLoader) load(certMod, keyMod time.Time) error {
+	cert, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+
+	l.cert.Store(&cert)
+	l.certMod = certMod
+	l.keyMod = keyMod
+
+	l.logger.Info(fmt.Sprintf("Loaded TLS certificate for %s HTTP server", l.server),
+		log.String("cert_file", l.certFile),
+		log.Time("not_after", cert.Leaf.NotAfter))
+	return nil
+}
+
+func (l *certLoader) modTimes() (time.Time, time.Time, error) {
+	certInfo, err := os.Stat(l.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("stat certificate: %w", err)
+	}
+	keyInfo, err := os.Stat(l.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("stat key: %w", err)
+	}
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
+
+// reloadIfChanged reloads the certificate if either the certificate or key file was modified since
+// they were last loaded.
+func (l *certLoader) reloadIfChanged() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	certMod, keyMod, err := l.modTimes()
+	if err != nil {
+		return err
+	}
+	if certMod.Equal(l.certMod) && keyMod.Equal(l.keyMod) {
+		return nil
+	}
+	return l.load(certMod, keyMod)
+}
+
+// Watch polls the certificate and key files for changes until the context is cancelled. If the
+// files cannot be loaded, the error is logged and the previous certificate continues to be served.
+func (l *certLoader) Watch(ctx context.Context) {
+	if l.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.reloadIfChanged(); err != nil {
+				l.logger.Error(fmt.Sprintf("Failed to reload TLS certificate for %s HTTP server", l.server),
+					log.Error(err))
+			}
+		}
+	}
+}
+
+// GetCertificate returns the current certificate. It implements the signature required by
+// tls.Config.GetCertificate.
+func (l *certLoader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert := l.cert.Load()
+	if cert == nil {
+		return nil, errors.New("no TLS certificate loaded")
+	}
+	return cert, nil
+}
+
+// NotAfter returns the expiration of the current certificate, or the zero value if no certificate
+// has been loaded.
+func (l *certLoader) NotAfter() time.Time {
+	cert := l.cert.Load()
+	if cert == nil || cert.Leaf == nil {
+		return time.Time{}
+	}
+	return cert.Leaf.NotAfter
+}
@@ -0,0 +1,112 @@
+package yuna
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/jkratz55/yuna/log"
+)
+
+// Typed adapts a function that accepts and returns typed values into a HandlerFunc, removing the
+// boilerplate of decoding the request and encoding the response.
+//
+// The Req value is populated from the request body using Request.Decode, and then from the URL
+// parameters, query string, headers, cookies, and form values of the request using the binding
+// tags described by Request.Bind. Unlike Request.Bind, fields without a binding tag aren't bound by
+// their name. If Req has a field tagged with `body`, only that field is populated from the request body, and
+// URL-encoded and multipart form bodies are only bound using the `form` tag. For example:
+//
+//	type UpdateUserRequest struct {
+//		ID     string `path:"id" json:"-"`
+//		DryRun bool   `query:"dryRun" json:"-"`
+//		Name   string `json:"name"`
+//	}
+//
+// If the request cannot be decoded the client receives a 400 Bad Request problem, or a 415
+// Unsupported Media Type problem if the Content-Type isn't supported. The Req value is
+// then validated the same way as Request.DecodeAndValidate, and if it isn't valid the client
+// receives a 422 Unprocessable Entity problem with the violations. The rules in the `validate`
+// struct tags of Req are checked when Typed is called, and Typed panics if any of them are invalid,
+// so a mistake in a tag is caught at startup rather than by the first request.
+//
+// If fn returns an error that is or wraps a *ProblemDetails, the problem is sent to the client.
+// Any other error is logged and results in a 500 Internal Server Error problem.
+//
+// If Resp implements Responder, for example *ResponseBuilder, it is used to respond as is, which
+// allows fn to control the status code and headers. Otherwise, the Resp value is sent using Ok. A nil Resp
+// results in a 204 No Content response.
+func Typed[Req, Resp any](fn func(ctx context.Context, req Req) (Resp, error)) HandlerFunc {
+	checkRules(reflect.TypeFor[Req]())
+
+	return func(r *Request) Responder {
+		var req Req
+		if problem := r.decodeTyped(&req); problem != nil {
+			return problem
+		}
+
+		resp, err := fn(r.Context(), req)
+		if err != nil {
+			return problemFromError(r.Context(), err)
+		}
+
+		if isNil(resp) {
+			return NoContent()
+		}
+		if responder, ok := any(resp).(Responder); ok {
+			return responder
+		}
+		return Ok(resp)
+	}
+}
+
+// decodeTyped populates dst from the body and parameters of the request for a Typed handler,
+// returning the problem to respond with if dst cannot be populated or isn't valid.
+func (r *Request) decodeTyped(dst any) *ProblemDetails {
+	// Unless a field is explicitly bound to the body, the body is decoded into the Req value itself.
+	if !hasBodyField(dst) && hasBody(r.raw) && !isForm(r.raw) {
+		if err := r.Decode(dst); err != nil && !errors.Is(err, io.EOF) {
+			return decodeProblem(err)
+		}
+	}
+
+	if err := r.bind(dst, false); err != nil {
+		var bindErr *BindError
+		if errors.As(err, &bindErr) {
+			return BadRequest(bindErr.Violations)
+		}
+		return decodeProblem(err)
+	}
+
+	return r.validate(dst)
+}
+
+func hasBody(r *http.Request) bool {
+	return r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0
+}
+
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return rv.IsNil()
+	default:
+		return false
+	}
+}
+
+// problemFromError maps an error returned by a handler to the problem sent to the client.
+func problemFromError(ctx context.Context, err error) *ProblemDetails {
+	var problem *ProblemDetails
+	if errors.As(err, &problem) {
+		return problem
+	}
+
+	log.LoggerFromCtx(ctx).Error("Handler returned an unhandled error", log.Error(err))
+	return InternalServerError(err)
+}
@@ -0,0 +1,508 @@
+package yuna
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// A Validator validates values decoded from requests.
+//
+// Implementations of Validator must be safe for concurrent use by multiple goroutines.
+type Validator interface {
+	// Validate validates v and returns the constraint violations found, or an empty Violations if v
+	// is valid. The names of fields in the Violations are taken from the struct tag with the key
+	// nameTag, such as "json", so they match the names the client sent.
+	Validate(v any, nameTag string) Violations
+}
+
+// Validatable is implemented by types that validate themselves. Validate returns the constraint
+// violations, or an empty Violations if the value is valid.
+//
+// Validate is called in addition to the Validator, which makes it the place for rules that cannot
+// be expressed with struct tags, such as rules spanning multiple fields.
+type Validatable interface {
+	Validate() Violations
+}
+
+// StructValidator is the default Validator. It validates structs using the rules in the `validate`
+// struct tag of their fields. Multiple rules are separated by commas:
+//
+//	type CreateUserRequest struct {
+//		Name    string   `json:"name" validate:"required,max=100"`
+//		Email   string   `json:"email" validate:"required,email"`
+//		Role    string   `json:"role" validate:"oneof=admin editor viewer"`
+//		Tags    []string `json:"tags" validate:"max=10,dive,min=1,max=20"`
+//		Address Address  `json:"address"`
+//	}
+//
+// The following rules are supported:
+//
+//   - required: the value must not be the zero value. Slices and maps must not be empty.
+//   - omitempty: the remaining rules are skipped if the value is the zero value.
+//   - min=n, max=n: the minimum and maximum value of numbers, the number of characters in strings,
+//     or the number of items in slices and maps.
+//   - len=n: the exact number of characters in strings, or items in slices and maps.
+//   - oneof=a b c: the value must be one of the space separated values.
+//   - email: the value must be an email address, without a display name.
+//   - uuid: the value must be a UUID.
+//   - regex=pattern: the value must match the regular expression. Since the pattern may contain
+//     commas, regex must be the last rule in the tag.
+//   - dive: the rules following dive apply to each item of a slice, array or map instead of the
+//     field itself.
+//
+// Nested structs, including pointers to structs and the items of slices and maps, are validated
+// recursively. Their violations are reported using the path to the field, such as
+// "address.street" or "items[2].quantity".
+//
+// Invalid rules, such as an unknown rule or a regex that doesn't compile, are programming errors
+// and cause Validate to panic. The rules of the request type of a Typed handler are checked when
+// Typed is called, so such errors surface when the route is registered.
+type StructValidator struct {
+	cache sync.Map // map[cacheKey][]fieldSpec
+}
+
+var _ Validator = (*StructValidator)(nil)
+
+// NewValidator creates a StructValidator.
+func NewValidator() *StructValidator {
+	return &StructValidator{}
+}
+
+// maxValidateDepth guards against cyclic data structures when validating nested structs.
+const maxValidateDepth = 32
+
+type cacheKey struct {
+	typ     reflect.Type
+	nameTag string
+}
+
+type fieldSpec struct {
+	index     int
+	name      string
+	embedded  bool
+	omitEmpty bool
+	rules     []rule
+	itemRules []rule
+}
+
+// A rule checks a value and returns a message describing the violation, or an empty string if the
+// value satisfies the rule.
+type rule struct {
+	name  string
+	check func(v reflect.Value) string
+}
+
+func (sv *StructValidator) Validate(v any, nameTag string) Violations {
+	violations := make(Violations)
+	sv.validate(reflect.ValueOf(v), "", nameTag, violations, 0)
+	return violations
+}
+
+func (sv *StructValidator) validate(v reflect.Value, path, nameTag string, violations Violations, depth int) {
+	if depth > maxValidateDepth {
+		return
+	}
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if isLeafType(v.Type()) {
+			return
+		}
+		for _, spec := range sv.fields(v.Type(), nameTag) {
+			fv := v.Field(spec.index)
+			if spec.embedded {
+				sv.validate(fv, path, nameTag, violations, depth+1)
+				continue
+			}
+
+			fieldPath := joinPath(path, spec.name)
+			if applyRules(fv, spec.rules, spec.omitEmpty, fieldPath, violations) && spec.itemRules != nil {
+				forEachItem(fv, fieldPath, func(item reflect.Value, itemPath string) {
+					applyRules(item, spec.itemRules, false, itemPath, violations)
+				})
+			}
+			sv.validate(fv, fieldPath, nameTag, violations, depth+1)
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		forEachItem(v, path, func(item reflect.Value, itemPath string) {
+			sv.validate(item, itemPath, nameTag, violations, depth+1)
+		})
+	}
+}
+
+// applyRules applies the rules to the value, adding any violations under the given path. It returns
+// false if the value failed the required rule, in which case the remaining rules aren't applied.
+func applyRules(v reflect.Value, rules []rule, omitEmpty bool, path string, violations Violations) bool {
+	if omitEmpty && isEmptyValue(v) {
+		return true
+	}
+	for _, r := range rules {
+		if r.name != "required" {
+			v = indirect(v)
+			if !v.IsValid() {
+				return true
+			}
+		}
+		if msg := r.check(v); msg != "" {
+			violations.Add(path, msg)
+			if r.name == "required" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func forEachItem(v reflect.Value, path string, fn func(item reflect.Value, itemPath string)) {
+	v = indirect(v)
+	if !v.IsValid() {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			fn(v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			fn(iter.Value(), fmt.Sprintf("%s[%v]", path, iter.Key()))
+		}
+	}
+}
+
+func (sv *StructValidator) fields(t reflect.Type, nameTag string) []fieldSpec {
+	key := cacheKey{typ: t, nameTag: nameTag}
+	if specs, ok := sv.cache.Load(key); ok {
+		return specs.([]fieldSpec)
+	}
+
+	specs := make([]fieldSpec, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := fieldName(field, nameTag)
+		tag := field.Tag.Get("validate")
+
+		if field.Anonymous && tag == "" && tagName(field, nameTag) == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				specs = append(specs, fieldSpec{index: i, embedded: true})
+				continue
+			}
+		}
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+		if _, ok := field.Tag.Lookup("body"); ok {
+			// The client sent the fields of a field bound to the body with Request.Bind at the top
+			// level of the body.
+			specs = append(specs, fieldSpec{index: i, embedded: true})
+			continue
+		}
+
+		spec := fieldSpec{index: i, name: name}
+		spec.rules, spec.itemRules, spec.omitEmpty = parseRules(t, field, tag)
+		specs = append(specs, spec)
+	}
+
+	actual, _ := sv.cache.LoadOrStore(key, specs)
+	return actual.([]fieldSpec)
+}
+
+// fieldName returns the name of the field as seen by the client: the name in the struct tag used for
+// decoding, falling back to the name of the parameter the field is bound to, and finally the name of
+// the field itself.
+func fieldName(field reflect.StructField, nameTag string) string {
+	for _, key := range []string{nameTag, "path", "query", "header", "cookie", "form"} {
+		if name := tagName(field, key); name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// checkRules parses the validation rules of the struct type and the structs nested in it, panicking
+// if any rule is invalid, just like StructValidator does when it first validates the type.
+func checkRules(t reflect.Type) {
+	checkRulesSeen(t, make(map[reflect.Type]bool))
+}
+
+func checkRulesSeen(t reflect.Type, seen map[reflect.Type]bool) {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || isLeafType(t) || seen[t] {
+		return
+	}
+	seen[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		if tag := field.Tag.Get("validate"); tag != "-" {
+			if _, ok := field.Tag.Lookup("body"); !ok {
+				parseRules(t, field, tag)
+			}
+		}
+		checkRulesSeen(field.Type, seen)
+	}
+}
+
+func parseRules(t reflect.Type, field reflect.StructField, tag string) (rules []rule, itemRules []rule, omitEmpty bool) {
+	if tag == "" {
+		return nil, nil, false
+	}
+
+	target := &rules
+	for tag != "" {
+		var def string
+		if strings.HasPrefix(tag, "regex=") {
+			def, tag = tag, ""
+		} else {
+			def, tag, _ = strings.Cut(tag, ",")
+		}
+		def = strings.TrimSpace(def)
+
+		name, param, _ := strings.Cut(def, "=")
+		switch name {
+		case "":
+			continue
+		case "omitempty":
+			if target == &rules {
+				omitEmpty = true
+			}
+			continue
+		case "dive":
+			if target == &itemRules {
+				panic(fmt.Sprintf("yuna: validation rule dive used more than once on %s.%s", t, field.Name))
+			}
+			itemRules = make([]rule, 0)
+			target = &itemRules
+			continue
+		}
+
+		check, err := newRuleCheck(name, param)
+		if err != nil {
+			panic(fmt.Sprintf("yuna: invalid validation rule %q on %s.%s: %v", def, t, field.Name, err))
+		}
+		*target = append(*target, rule{name: name, check: check})
+	}
+	return rules, itemRules, omitEmpty
+}
+
+func newRuleCheck(name, param string) (func(v reflect.Value) string, error) {
+	switch name {
+	case "required":
+		return func(v reflect.Value) string {
+			if isEmptyValue(v) {
+				return "is required"
+			}
+			return ""
+		}, nil
+	case "min", "max":
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parameter must be a number")
+		}
+		return boundCheck(name, limit), nil
+	case "len":
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return nil, fmt.Errorf("parameter must be an integer")
+		}
+		return func(v reflect.Value) string {
+			switch v.Kind() {
+			case reflect.String:
+				if utf8.RuneCountInString(v.String()) != n {
+					return fmt.Sprintf("must be exactly %d characters long", n)
+				}
+			case reflect.Slice, reflect.Array, reflect.Map:
+				if v.Len() != n {
+					return fmt.Sprintf("must contain exactly %d items", n)
+				}
+			}
+			return ""
+		}, nil
+	case "oneof":
+		allowed := strings.Fields(param)
+		if len(allowed) == 0 {
+			return nil, fmt.Errorf("at least one value is required")
+		}
+		return func(v reflect.Value) string {
+			if s, ok := scalarString(v); ok && !slices.Contains(allowed, s) {
+				return fmt.Sprintf("must be one of: %s", strings.Join(allowed, ", "))
+			}
+			return ""
+		}, nil
+	case "email":
+		return stringCheck(func(s string) bool {
+			addr, err := mail.ParseAddress(s)
+			return err == nil && addr.Address == s
+		}, "must be a valid email address"), nil
+	case "uuid":
+		return stringCheck(func(s string) bool {
+			return uuid.Validate(s) == nil
+		}, "must be a valid UUID"), nil
+	case "regex":
+		re, err := regexp.Compile(param)
+		if err != nil {
+			return nil, err
+		}
+		return stringCheck(re.MatchString, fmt.Sprintf("must match the pattern %s", param)), nil
+	default:
+		return nil, fmt.Errorf("unknown rule")
+	}
+}
+
+func boundCheck(name string, limit float64) func(v reflect.Value) string {
+	violates := func(n float64) bool {
+		if name == "min" {
+			return n < limit
+		}
+		return n > limit
+	}
+	bound := "at least"
+	if name == "max" {
+		bound = "at most"
+	}
+	limitStr := strconv.FormatFloat(limit, 'f', -1, 64)
+
+	return func(v reflect.Value) string {
+		switch v.Kind() {
+		case reflect.String:
+			if violates(float64(utf8.RuneCountInString(v.String()))) {
+				return fmt.Sprintf("must be %s %s characters long", bound, limitStr)
+			}
+		case reflect.Slice, reflect.Array, reflect.Map:
+			if violates(float64(v.Len())) {
+				return fmt.Sprintf("must contain %s %s items", bound, limitStr)
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if violates(float64(v.Int())) {
+				return fmt.Sprintf("must be %s %s", bound, limitStr)
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if violates(float64(v.Uint())) {
+				return fmt.Sprintf("must be %s %s", bound, limitStr)
+			}
+		case reflect.Float32, reflect.Float64:
+			if violates(v.Float()) {
+				return fmt.Sprintf("must be %s %s", bound, limitStr)
+			}
+		}
+		return ""
+	}
+}
+
+func stringCheck(valid func(s string) bool, msg string) func(v reflect.Value) string {
+	return func(v reflect.Value) string {
+		if v.Kind() == reflect.String && !valid(v.String()) {
+			return msg
+		}
+		return ""
+	}
+}
+
+func scalarString(v reflect.Value) (string, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true
+	default:
+		return "", false
+	}
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	case reflect.Invalid:
+		return true
+	default:
+		return v.IsZero()
+	}
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// isLeafType reports whether the struct type is validated as a single value rather than by its fields,
+// such as time.Time.
+func isLeafType(t reflect.Type) bool {
+	return t == timeType || reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// validateValue validates the value pointed to by ptr using the Validator and, if implemented, the
+// Validate method of the value itself.
+func validateValue(validator Validator, ptr any, nameTag string) Violations {
+	violations := make(Violations)
+	if validator != nil {
+		for field, msgs := range validator.Validate(ptr, nameTag) {
+			violations.Add(field, msgs...)
+		}
+	}
+	if v, ok := validatable(ptr); ok {
+		for field, msgs := range v.Validate() {
+			violations.Add(field, msgs...)
+		}
+	}
+	return violations
+}
+
+// validatable returns the Validatable implementation of the value pointed to by ptr, whether it is
+// implemented on the value or pointer receiver.
+func validatable(ptr any) (Validatable, bool) {
+	rv := reflect.ValueOf(ptr)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		if v, ok := rv.Interface().(Validatable); ok {
+			return v, true
+		}
+		rv = rv.Elem()
+	}
+	if rv.IsValid() && rv.CanInterface() {
+		v, ok := rv.Interface().(Validatable)
+		return v, ok
+	}
+	return nil, false
+}
@@ -0,0 +1,264 @@
+package yuna
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRules(t *testing.T) {
+	tests := []struct {
+		tag           string
+		wantRules     []string
+		wantItemRules []string
+		wantOmitEmpty bool
+	}{
+		{tag: "", wantRules: nil},
+		{tag: "required", wantRules: []string{"required"}},
+		{tag: "required, max=100", wantRules: []string{"required", "max"}},
+		{tag: "omitempty,email", wantRules: []string{"email"}, wantOmitEmpty: true},
+		{tag: "min=1.5,max=-2,len=3", wantRules: []string{"min", "max", "len"}},
+		{tag: "oneof=admin editor viewer", wantRules: []string{"oneof"}},
+		{tag: "uuid,,", wantRules: []string{"uuid"}},
+		{tag: "max=10,dive,min=1,max=20", wantRules: []string{"max"}, wantItemRules: []string{"min", "max"}},
+		{tag: "dive,omitempty,required", wantRules: nil, wantItemRules: []string{"required"}},
+		{tag: "required,regex=^[a-z]{1,3},[0-9]+$", wantRules: []string{"required", "regex"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			rules, itemRules, omitEmpty := parseRules(reflect.TypeFor[struct{}](), reflect.StructField{Name: "Field"}, tt.tag)
+			if got := ruleNames(rules); !reflect.DeepEqual(got, tt.wantRules) {
+				t.Errorf("rules = %v, want %v", got, tt.wantRules)
+			}
+			if got := ruleNames(itemRules); !reflect.DeepEqual(got, tt.wantItemRules) {
+				t.Errorf("item rules = %v, want %v", got, tt.wantItemRules)
+			}
+			if omitEmpty != tt.wantOmitEmpty {
+				t.Errorf("omitEmpty = %t, want %t", omitEmpty, tt.wantOmitEmpty)
+			}
+		})
+	}
+}
+
+func ruleNames(rules []rule) []string {
+	if rules == nil {
+		return nil
+	}
+	names := make([]string, 0, len(rules))
+	for _, r := range rules {
+		names = append(names, r.name)
+	}
+	return names
+}
+
+func TestParseRulesInvalid(t *testing.T) {
+	tests := []struct {
+		tag       string
+		wantPanic string
+	}{
+		{tag: "requird", wantPanic: `invalid validation rule "requird" on struct {}.Field: unknown rule`},
+		{tag: "min=one", wantPanic: "parameter must be a number"},
+		{tag: "max=", wantPanic: "parameter must be a number"},
+		{tag: "len=1.5", wantPanic: "parameter must be an integer"},
+		{tag: "oneof=", wantPanic: "at least one value is required"},
+		{tag: "regex=[a-z", wantPanic: "missing closing ]"},
+		{tag: "dive,dive", wantPanic: "dive used more than once on struct {}.Field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					t.Fatal("no panic for an invalid rule")
+				}
+				if msg := r.(string); !strings.Contains(msg, tt.wantPanic) {
+					t.Errorf("panic = %q, want %q", msg, tt.wantPanic)
+				}
+			}()
+			parseRules(reflect.TypeFor[struct{}](), reflect.StructField{Name: "Field"}, tt.tag)
+		})
+	}
+}
+
+type validateAddress struct {
+	Street string `json:"street" validate:"required"`
+	Zip    string `json:"zip" validate:"omitempty,len=5"`
+}
+
+type validateBase struct {
+	ID string `json:"id" validate:"omitempty,uuid"`
+}
+
+type validateUser struct {
+	validateBase
+	Name      string                     `json:"name" validate:"required,max=5"`
+	Email     string                     `json:"email" validate:"email"`
+	Age       int                        `json:"age" validate:"min=18"`
+	Role      string                     `json:"role" validate:"oneof=admin viewer"`
+	Code      string                     `json:"code" validate:"regex=^[A-Z]{2,3}$"`
+	Tags      []string                   `json:"tags" validate:"max=2,dive,min=2"`
+	Nickname  *string                    `json:"nickname" validate:"omitempty,min=3"`
+	Address   *validateAddress           `json:"address"`
+	Addresses map[string]validateAddress `json:"addresses"`
+	Since     time.Time                  `json:"since" validate:"required"`
+	Limit     int                        `query:"limit" validate:"max=100"`
+	Ignored   string                     `json:"ignored" validate:"-"`
+	internal  string                     `validate:"required"`
+}
+
+func TestStructValidator(t *testing.T) {
+	short := "ab"
+	valid := func() validateUser {
+		return validateUser{
+			Name:  "Ann",
+			Email: "ann@example.com",
+			Age:   30,
+			Role:  "admin",
+			Code:  "ABC",
+			Tags:  []string{"go", "api"},
+			Since: time.Now(),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(u *validateUser)
+		want   Violations
+	}{
+		{
+			name:   "valid",
+			modify: func(u *validateUser) {},
+			want:   Violations{},
+		},
+		{
+			name: "required and rules after it",
+			modify: func(u *validateUser) {
+				u.Name = ""
+				u.Since = time.Time{}
+			},
+			want: Violations{"name": {"is required"}, "since": {"is required"}},
+		},
+		{
+			name: "bounds",
+			modify: func(u *validateUser) {
+				u.Name = "Annabel"
+				u.Age = 17
+				u.Limit = 101
+				u.Tags = []string{"a", "b", "c"}
+			},
+			want: Violations{
+				"name":    {"must be at most 5 characters long"},
+				"age":     {"must be at least 18"},
+				"limit":   {"must be at most 100"},
+				"tags":    {"must contain at most 2 items"},
+				"tags[0]": {"must be at least 2 characters long"},
+				"tags[1]": {"must be at least 2 characters long"},
+				"tags[2]": {"must be at least 2 characters long"},
+			},
+		},
+		{
+			name: "formats",
+			modify: func(u *validateUser) {
+				u.ID = "not-a-uuid"
+				u.Email = "Ann <ann@example.com>"
+				u.Role = "owner"
+				u.Code = "abc"
+			},
+			want: Violations{
+				"id":    {"must be a valid UUID"},
+				"email": {"must be a valid email address"},
+				"role":  {"must be one of: admin, viewer"},
+				"code":  {"must match the pattern ^[A-Z]{2,3}$"},
+			},
+		},
+		{
+			name: "omitempty pointer",
+			modify: func(u *validateUser) {
+				u.Nickname = &short
+			},
+			want: Violations{"nickname": {"must be at least 3 characters long"}},
+		},
+		{
+			name: "nested structs",
+			modify: func(u *validateUser) {
+				u.Address = &validateAddress{Zip: "123"}
+				u.Addresses = map[string]validateAddress{"home": {Street: "Main St", Zip: "12345"}, "work": {}}
+			},
+			want: Violations{
+				"address.street":         {"is required"},
+				"address.zip":            {"must be exactly 5 characters long"},
+				"addresses[work].street": {"is required"},
+			},
+		},
+	}
+
+	sv := NewValidator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := valid()
+			tt.modify(&u)
+			if got := sv.Validate(&u, "json"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckRules(t *testing.T) {
+	type node struct {
+		Children []*node `validate:"max=10"`
+	}
+	type invalidItem struct {
+		Name string `validate:"requird"`
+	}
+	type invalidBody struct {
+		Items map[string][]invalidItem
+	}
+	type unexportedEmbedded struct {
+		invalidItem
+	}
+
+	tests := []struct {
+		name      string
+		typ       reflect.Type
+		wantPanic bool
+	}{
+		{name: "valid", typ: reflect.TypeFor[validateUser]()},
+		{name: "cyclic", typ: reflect.TypeFor[node]()},
+		{name: "not a struct", typ: reflect.TypeFor[[]string]()},
+		{name: "invalid", typ: reflect.TypeFor[invalidItem](), wantPanic: true},
+		{name: "invalid nested in collections", typ: reflect.TypeFor[*invalidBody](), wantPanic: true},
+		{name: "invalid in unexported embedded struct", typ: reflect.TypeFor[unexportedEmbedded](), wantPanic: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); (r != nil) != tt.wantPanic {
+					t.Errorf("panic = %v, want panic %t", r, tt.wantPanic)
+				}
+			}()
+			checkRules(tt.typ)
+		})
+	}
+}
+
+func TestTypedChecksRules(t *testing.T) {
+	type createUser struct {
+		Email string `json:"email" validate:"required,emial"`
+	}
+
+	defer func() {
+		r := recover()
+		if r == nil || !strings.Contains(r.(string), `invalid validation rule "emial"`) {
+			t.Errorf("panic = %v, want invalid validation rule", r)
+		}
+	}()
+	Typed(func(ctx context.Context, req createUser) (*createUser, error) {
+		return &req, nil
+	})
+}
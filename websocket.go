@@ -0,0 +1,375 @@
+package yuna
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/jkratz55/yuna/internal"
+)
+
+const (
+	defaultWebSocketReadLimit    = 1 << 20
+	defaultWebSocketPingInterval = 30 * time.Second
+	defaultWebSocketWriteTimeout = 10 * time.Second
+)
+
+// websocketGUID is appended to the Sec-WebSocket-Key to compute the Sec-WebSocket-Accept header,
+// as defined by RFC 6455.
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocketResponder is a Responder upgrading the connection to a WebSocket, as defined by RFC
+// 6455, and handing it to a handler for the lifetime of the connection.
+//
+// Since the upgrade happens when the Handler returns the Responder, the middleware of the route,
+// including Authenticate, Authenticated, and RequireRole, has already been applied to the opening
+// handshake. Requests that fail the handshake receive a problem response instead.
+//
+// WebSocketResponder disables the read and write timeouts of the server for the connection, since
+// it is expected to outlive them. Instead, the client is pinged periodically and the connection is
+// closed if the client stops responding.
+type WebSocketResponder struct {
+	handler      func(conn *WebSocketConn) error
+	subprotocols []string
+	origins      []string
+	compression  bool
+	readLimit    int64
+	pingInterval time.Duration
+	writeTimeout time.Duration
+}
+
+// WebSocket returns a Responder upgrading the connection to a WebSocket and calling handler with the
+// connection. The connection is closed when handler returns, with the close code 1000 Normal
+// Closure if handler returns nil or 1011 Internal Error otherwise.
+//
+//	router.Get("/chat", func(r *yuna.Request) yuna.Responder {
+//		return yuna.WebSocket(func(conn *yuna.WebSocketConn) error {
+//			for {
+//				var msg ChatMessage
+//				if err := conn.ReadJSON(conn.Context(), &msg); err != nil {
+//					return err
+//				}
+//				hub.Publish("chat", yuna.Event{Data: msg})
+//			}
+//		})
+//	})
+//
+// Returning the *CloseError reported by WebSocketConn when the client closes the connection is
+// not considered an error.
+func WebSocket(handler func(conn *WebSocketConn) error) *WebSocketResponder {
+	if handler == nil {
+		panic("websocket handler cannot be nil")
+	}
+	return &WebSocketResponder{
+		handler:      handler,
+		readLimit:    defaultWebSocketReadLimit,
+		pingInterval: defaultWebSocketPingInterval,
+		writeTimeout: defaultWebSocketWriteTimeout,
+	}
+}
+
+// Subprotocols sets the subprotocols supported by the handler, in order of preference. The first
+// one also requested by the client in the Sec-WebSocket-Protocol header is selected, see
+// WebSocketConn.Subprotocol. If the client doesn't request any of them, the handshake fails with
+// 400 Bad Request.
+func (ws *WebSocketResponder) Subprotocols(protocols ...string) *WebSocketResponder {
+	ws.subprotocols = protocols
+	return ws
+}
+
+// AllowOrigins sets the origins browsers are allowed to open the WebSocket from. The patterns are
+// matched against the host, including the port if any, of the Origin header using path.Match, for
+// example "app.example.com" or "*.example.com". The pattern "*" allows any origin.
+//
+// By default, only the origin of the server itself is allowed, which protects against cross-site
+// WebSocket hijacking since browsers send cookies with the handshake. Requests without an Origin
+// header aren't sent by browsers and are always allowed.
+func (ws *WebSocketResponder) AllowOrigins(patterns ...string) *WebSocketResponder {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			panic(fmt.Sprintf("invalid origin pattern %q", pattern))
+		}
+	}
+	ws.origins = patterns
+	return ws
+}
+
+// Compression enables the permessage-deflate extension defined by RFC 7692 if the client supports
+// it. Compression is negotiated without context takeover, so messages are compressed individually,
+// trading compression ratio for memory usage. Messages smaller than 128 bytes written with
+// WriteMessage are not compressed. Compression is disabled by default.
+func (ws *WebSocketResponder) Compression(enabled bool) *WebSocketResponder {
+	ws.compression = enabled
+	return ws
+}
+
+// ReadLimit sets the maximum size of a message received from the client in bytes. When a larger
+// message is received the connection is closed with the close code 1009 Message Too Big. For
+// compressed messages, the limit applies both before and after decompressing. The default is 1 MiB,
+// a limit of zero or less disables it.
+func (ws *WebSocketResponder) ReadLimit(n int64) *WebSocketResponder {
+	ws.readLimit = n
+	return ws
+}
+
+// PingInterval sets the interval pings are sent to the client. If nothing is received from the
+// client for twice the interval the connection is closed. The default is 30 seconds. An interval
+// of zero disables pings, in which case a client that stopped responding is only detected when
+// writing to it fails.
+func (ws *WebSocketResponder) PingInterval(interval time.Duration) *WebSocketResponder {
+	ws.pingInterval = interval
+	return ws
+}
+
+// WriteTimeout sets the maximum time writing a frame to the client may take when the context
+// passed to the write doesn't have an earlier deadline. The default is 10 seconds, a timeout of
+// zero disables it.
+func (ws *WebSocketResponder) WriteTimeout(timeout time.Duration) *WebSocketResponder {
+	ws.writeTimeout = timeout
+	return ws
+}
+
+func (ws *WebSocketResponder) Respond(w http.ResponseWriter, r *http.Request) error {
+	if problem := ws.checkHandshake(r); problem != nil {
+		return problem.Respond(w, r)
+	}
+	subprotocol, ok := ws.selectSubprotocol(r)
+	if !ok {
+		return BadRequest(nil).
+			SetDetail(fmt.Sprintf("The WebSocket requires one of the subprotocols %s.",
+				strings.Join(ws.subprotocols, ", "))).
+			Respond(w, r)
+	}
+	compress := ws.compression && deflateOffered(r.Header)
+
+	netConn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return InternalServerError(fmt.Errorf("websocket: hijack: %w", err)).Respond(w, r)
+	}
+
+	// The deadlines set by the server for the request would otherwise apply to the connection.
+	_ = netConn.SetDeadline(time.Time{})
+
+	// Headers already set, such as X-Request-ID, are sent with the handshake response.
+	header := w.Header().Clone()
+	header.Set(HeaderUpgrade, "websocket")
+	header.Set(HeaderConnection, "Upgrade")
+	header.Set(HeaderSecWebSocketAccept, websocketAccept(r.Header.Get(HeaderSecWebSocketKey)))
+	if subprotocol != "" {
+		header.Set(HeaderSecWebSocketProtocol, subprotocol)
+	}
+	if compress {
+		header.Set(HeaderSecWebSocketExtensions,
+			"permessage-deflate; server_no_context_takeover; client_no_context_takeover")
+	}
+
+	bw := bufio.NewWriterSize(netConn, 4096)
+	_ = netConn.SetWriteDeadline(time.Now().Add(ws.writeTimeout))
+	_, _ = bw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	_ = header.Write(bw)
+	_, _ = bw.WriteString("\r\n")
+	if err := bw.Flush(); err != nil {
+		_ = netConn.Close()
+		return fmt.Errorf("websocket: write handshake: %w", err)
+	}
+
+	ctx := r.Context()
+	settings := settingsFromCtx(ctx)
+	attrs := metric.WithAttributes(routeAttr(r))
+	settings.metrics.wsConns.Add(ctx, 1, attrs)
+	defer settings.metrics.wsConns.Add(context.WithoutCancel(ctx), -1, attrs)
+
+	// The span of the request ends when the connection is closed, this span covers the connection
+	// after the handshake.
+	tracer := trace.SpanFromContext(ctx).TracerProvider().
+		Tracer(internal.Scope, trace.WithInstrumentationVersion(internal.Version))
+	ctx, span := tracer.Start(ctx, "WebSocket", trace.WithAttributes(
+		routeAttr(r),
+		attribute.String("websocket.subprotocol", subprotocol),
+		attribute.Bool("websocket.compression", compress)))
+	defer span.End()
+
+	conn := newWebSocketConn(ctx, netConn, brw.Reader, bw, webSocketConnConfig{
+		subprotocol:  subprotocol,
+		compress:     compress,
+		readLimit:    ws.readLimit,
+		pingInterval: ws.pingInterval,
+		writeTimeout: ws.writeTimeout,
+		codec:        jsonCodec(r),
+		metrics:      settings.metrics,
+		attrs:        attrs,
+	})
+
+	err = ws.serve(conn)
+	var closeErr *CloseError
+	if err != nil && !errors.As(err, &closeErr) && !errors.Is(err, context.Canceled) {
+		_ = conn.Close(CloseInternalError, "")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		_ = conn.Close(CloseNormalClosure, "")
+		err = nil
+	}
+
+	span.SetAttributes(
+		attribute.Int64("websocket.messages.received", conn.received.Load()),
+		attribute.Int64("websocket.messages.sent", conn.sent.Load()),
+		attribute.Int64("websocket.messages.dropped", conn.dropped.Load()),
+		attribute.Int("websocket.close.code", int(conn.closeCode())))
+	if err != nil {
+		return fmt.Errorf("websocket: handler: %w", err)
+	}
+	return nil
+}
+
+// serve calls the handler, closing the connection with 1011 Internal Error if it panics before
+// the panic is passed on to the recovery middleware.
+func (ws *WebSocketResponder) serve(conn *WebSocketConn) error {
+	defer func() {
+		if v := recover(); v != nil {
+			_ = conn.Close(CloseInternalError, "")
+			panic(v)
+		}
+	}()
+	return ws.handler(conn)
+}
+
+// checkHandshake validates the opening handshake of the client, returning the problem to respond
+// with if it isn't valid.
+func (ws *WebSocketResponder) checkHandshake(r *http.Request) *ProblemDetails {
+	if r.Method != http.MethodGet {
+		return MethodNotAllowed(http.MethodGet)
+	}
+	if r.ProtoMajor != 1 || r.ProtoMinor < 1 ||
+		!headerHasToken(r.Header, HeaderConnection, "upgrade") ||
+		!headerHasToken(r.Header, HeaderUpgrade, "websocket") {
+		return upgradeRequired(HeaderUpgrade, "websocket").
+			SetDetail("The resource requires a WebSocket connection.")
+	}
+	if r.Header.Get(HeaderSecWebSocketVersion) != "13" {
+		return upgradeRequired(HeaderSecWebSocketVersion, "13").
+			SetDetail("Only version 13 of the WebSocket protocol is supported.")
+	}
+	if key, err := base64.StdEncoding.DecodeString(r.Header.Get(HeaderSecWebSocketKey)); err != nil || len(key) != 16 {
+		return BadRequest(nil).SetDetail("The Sec-WebSocket-Key header is missing or invalid.")
+	}
+	if !ws.allowOrigin(r) {
+		return Forbidden().SetDetail("The origin is not allowed to open a WebSocket.")
+	}
+	return nil
+}
+
+// allowOrigin reports whether the Origin of the request is allowed.
+func (ws *WebSocketResponder) allowOrigin(r *http.Request) bool {
+	origin := r.Header.Get(HeaderOrigin)
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if len(ws.origins) == 0 {
+		return strings.EqualFold(u.Host, r.Host)
+	}
+	for _, pattern := range ws.origins {
+		if ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(u.Host)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// selectSubprotocol returns the preferred subprotocol requested by the client, and false if none
+// of the supported subprotocols were requested.
+func (ws *WebSocketResponder) selectSubprotocol(r *http.Request) (string, bool) {
+	if len(ws.subprotocols) == 0 {
+		return "", true
+	}
+	for _, protocol := range ws.subprotocols {
+		for _, value := range r.Header.Values(HeaderSecWebSocketProtocol) {
+			for requested := range strings.SplitSeq(value, ",") {
+				if strings.TrimSpace(requested) == protocol {
+					return protocol, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// deflateOffered reports whether the client offered the permessage-deflate extension with
+// parameters the server can accept. Since compress/flate always uses a 32 KiB window, offers
+// restricting the window of the server are declined.
+func deflateOffered(h http.Header) bool {
+	for _, value := range h.Values(HeaderSecWebSocketExtensions) {
+	offers:
+		for offer := range strings.SplitSeq(value, ",") {
+			params := strings.Split(offer, ";")
+			if !strings.EqualFold(strings.TrimSpace(params[0]), "permessage-deflate") {
+				continue
+			}
+			for _, param := range params[1:] {
+				name, value, _ := strings.Cut(param, "=")
+				value = strings.Trim(strings.TrimSpace(value), `"`)
+				switch strings.ToLower(strings.TrimSpace(name)) {
+				case "server_no_context_takeover", "client_no_context_takeover", "client_max_window_bits":
+				case "server_max_window_bits":
+					if value != "15" {
+						continue offers
+					}
+				default:
+					continue offers
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// websocketAccept computes the Sec-WebSocket-Accept header for the Sec-WebSocket-Key of the client.
+func websocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// headerHasToken reports whether the comma separated values of the header contain the token,
+// compared case-insensitively.
+func headerHasToken(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for t := range strings.SplitSeq(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// upgradeRequired returns a 426 Upgrade Required problem, sending the header telling the client
+// which protocol to switch to.
+func upgradeRequired(header, value string) *ProblemDetails {
+	return &ProblemDetails{
+		Type:       "about:blank",
+		Title:      "Upgrade Required",
+		StatusCode: http.StatusUpgradeRequired,
+		Extensions: make(map[string]interface{}),
+		headers: http.Header{
+			header: {value},
+		},
+	}
+}
@@ -0,0 +1,794 @@
+package yuna
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// A MessageType is the type of a WebSocket message.
+type MessageType int
+
+const (
+	// TextMessage is a message containing UTF-8 encoded text.
+	TextMessage MessageType = 1
+
+	// BinaryMessage is a message containing binary data.
+	BinaryMessage MessageType = 2
+)
+
+// A CloseCode is the status code sent in a WebSocket close frame, as defined by RFC 6455.
+type CloseCode uint16
+
+const (
+	CloseNormalClosure           CloseCode = 1000
+	CloseGoingAway               CloseCode = 1001
+	CloseProtocolError           CloseCode = 1002
+	CloseUnsupportedData         CloseCode = 1003
+	CloseNoStatusReceived        CloseCode = 1005
+	CloseAbnormalClosure         CloseCode = 1006
+	CloseInvalidFramePayloadData CloseCode = 1007
+	ClosePolicyViolation         CloseCode = 1008
+	CloseMessageTooBig           CloseCode = 1009
+	CloseMandatoryExtension      CloseCode = 1010
+	CloseInternalError           CloseCode = 1011
+	CloseServiceRestart          CloseCode = 1012
+	CloseTryAgainLater           CloseCode = 1013
+)
+
+// valid reports whether the code may be sent in a close frame. The codes 1005 and 1006 are only
+// reported to the application, and codes in the range 1000-2999 that aren't assigned are reserved.
+func (c CloseCode) valid() bool {
+	switch {
+	case c >= 1000 && c <= 1003, c >= 1007 && c <= 1014:
+		return true
+	case c >= 3000 && c <= 4999:
+		return true
+	}
+	return false
+}
+
+// CloseError is the error returned by WebSocketConn when the connection is closed. Code is the
+// close code sent by the client, the code sent by the server if the client violated the protocol,
+// or CloseAbnormalClosure if the connection was lost without a close frame.
+type CloseError struct {
+	Code   CloseCode
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("websocket: closed with code %d", e.Code)
+	}
+	return fmt.Sprintf("websocket: closed with code %d: %s", e.Code, e.Reason)
+}
+
+func protocolError(reason string) *CloseError {
+	return &CloseError{Code: CloseProtocolError, Reason: reason}
+}
+
+// Frame opcodes defined by RFC 6455.
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+const (
+	// maxControlPayload is the maximum size of the payload of control frames.
+	maxControlPayload = 125
+
+	// maxFramePayload is the size of the fragments messages are written in.
+	maxFramePayload = 32 << 10
+
+	// minCompressSize is the size below which compressing a message isn't worth the cost.
+	minCompressSize = 128
+
+	// closeTimeout is how long to wait for the client to acknowledge the close frame.
+	closeTimeout = 5 * time.Second
+
+	// messageQueueSize is the number of received messages queued until the handler reads them.
+	messageQueueSize = 16
+)
+
+// deflateTail is appended to compressed messages before inflating them. The first 4 bytes are the
+// end of the sync flush removed by the sender, followed by an empty final block so the reader sees
+// the end of the stream.
+var deflateTail = []byte{0x00, 0x00, 0xff, 0xff, 0x01, 0x00, 0x00, 0xff, 0xff}
+
+var (
+	flateReaders sync.Pool
+	flateWriters sync.Pool
+)
+
+// WebSocketConn is a WebSocket connection upgraded by WebSocketResponder.
+//
+// Messages are received in the background, which also answers pings and handles the close frame of
+// the client, so the connection is closed promptly even while the handler is only writing. Up to 16
+// received messages are queued until the handler reads them. Messages received while the queue is
+// full are discarded, which is reported by the websocket.messages.dropped attribute of the
+// WebSocket span, so handlers expecting messages should keep reading. Reading and writing are safe
+// for concurrent use: messages are read by one goroutine at a time, and concurrent writes are sent
+// one message after another.
+type WebSocketConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	bw   *bufio.Writer
+	hdr  [14]byte
+	conf webSocketConnConfig
+
+	readLimit atomic.Int64
+	received  atomic.Int64
+	dropped   atomic.Int64
+	sent      atomic.Int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	messages chan wsMessage
+	readDone chan struct{}
+	readErr  error
+
+	writeSem  chan struct{} // held while a message is written, so fragments aren't interleaved
+	writeMu   sync.Mutex    // held while a frame is written
+	closing   chan struct{} // closed when the close frame is sent
+	closeSent bool
+	code      atomic.Uint32
+	closeOnce sync.Once
+}
+
+type webSocketConnConfig struct {
+	subprotocol  string
+	compress     bool
+	readLimit    int64
+	pingInterval time.Duration
+	writeTimeout time.Duration
+	codec        Codec
+	metrics      *responderMetrics
+	attrs        metric.MeasurementOption
+}
+
+type wsMessage struct {
+	typ  MessageType
+	data []byte
+}
+
+func newWebSocketConn(ctx context.Context, conn net.Conn, br *bufio.Reader, bw *bufio.Writer, conf webSocketConnConfig) *WebSocketConn {
+	c := &WebSocketConn{
+		conn:     conn,
+		br:       br,
+		bw:       bw,
+		conf:     conf,
+		messages: make(chan wsMessage, messageQueueSize),
+		readDone: make(chan struct{}),
+		writeSem: make(chan struct{}, 1),
+		closing:  make(chan struct{}),
+	}
+	c.readLimit.Store(conf.readLimit)
+	c.ctx, c.cancel = context.WithCancel(ctx)
+
+	go c.readLoop()
+	if conf.pingInterval > 0 {
+		go c.pingLoop()
+	}
+	return c
+}
+
+// Context returns a context that is done when the connection is closed.
+func (c *WebSocketConn) Context() context.Context {
+	return c.ctx
+}
+
+// Subprotocol returns the subprotocol selected during the handshake, or an empty string if none
+// was.
+func (c *WebSocketConn) Subprotocol() string {
+	return c.conf.subprotocol
+}
+
+// SetReadLimit sets the maximum size of messages received from the client, overriding
+// WebSocketResponder.ReadLimit. It applies to messages the client hasn't started sending yet.
+func (c *WebSocketConn) SetReadLimit(n int64) {
+	c.readLimit.Store(n)
+}
+
+// ReadMessage returns the next message received from the client. It blocks until a message is
+// received, the connection is closed, or ctx is done. When the connection is closed a *CloseError
+// is returned.
+func (c *WebSocketConn) ReadMessage(ctx context.Context) (MessageType, []byte, error) {
+	select {
+	case msg := <-c.messages:
+		return msg.typ, msg.data, nil
+	case <-c.readDone:
+		return c.queuedMessage(c.readErr)
+	case <-ctx.Done():
+		// The context of the connection is done once it is closed, in which case the reason is
+		// reported instead.
+		select {
+		case <-c.readDone:
+			return c.queuedMessage(c.readErr)
+		default:
+			return 0, nil, ctx.Err()
+		}
+	}
+}
+
+// queuedMessage returns the next queued message after the connection was closed, so messages
+// received before the close frame aren't lost, or err once the queue is empty.
+func (c *WebSocketConn) queuedMessage(err error) (MessageType, []byte, error) {
+	select {
+	case msg := <-c.messages:
+		return msg.typ, msg.data, nil
+	default:
+		return 0, nil, err
+	}
+}
+
+// ReadJSON reads the next message and decodes it as JSON into v, using the Codec registered for
+// application/json.
+func (c *WebSocketConn) ReadJSON(ctx context.Context, v any) error {
+	_, data, err := c.ReadMessage(ctx)
+	if err != nil {
+		return err
+	}
+	return c.conf.codec.Decode(bytes.NewReader(data), v)
+}
+
+// WriteMessage sends a message to the client. If ctx is done before the message is written, the
+// connection is closed, since the client cannot recover from a partially written frame.
+func (c *WebSocketConn) WriteMessage(ctx context.Context, typ MessageType, data []byte) error {
+	w, err := c.writer(ctx, typ, c.conf.compress && len(data) >= minCompressSize)
+	if err != nil {
+		return err
+	}
+	_, _ = w.Write(data)
+	return w.Close()
+}
+
+// WriteJSON encodes v as JSON, using the Codec registered for application/json, and sends it to
+// the client as a text message.
+func (c *WebSocketConn) WriteJSON(ctx context.Context, v any) error {
+	var buf bytes.Buffer
+	if err := c.conf.codec.Encode(&buf, v); err != nil {
+		return err
+	}
+	return c.WriteMessage(ctx, TextMessage, bytes.TrimRight(buf.Bytes(), "\n"))
+}
+
+// Writer returns a writer for a message sent to the client in fragments as it is written, for
+// messages that are too large to hold in memory. The message is complete when the writer is
+// closed, other messages are held back until then.
+func (c *WebSocketConn) Writer(ctx context.Context, typ MessageType) (io.WriteCloser, error) {
+	return c.writer(ctx, typ, c.conf.compress)
+}
+
+func (c *WebSocketConn) writer(ctx context.Context, typ MessageType, compress bool) (*messageWriter, error) {
+	if typ != TextMessage && typ != BinaryMessage {
+		panic(fmt.Sprintf("invalid websocket message type %d", typ))
+	}
+
+	select {
+	case c.writeSem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-c.ctx.Done():
+		return nil, c.closedError()
+	}
+
+	w := &messageWriter{
+		c:          c,
+		ctx:        ctx,
+		opcode:     byte(typ),
+		compressed: compress,
+	}
+	if compress {
+		fw, _ := flateWriters.Get().(*flate.Writer)
+		if fw == nil {
+			fw, _ = flate.NewWriter(writerFunc(w.buffer), flate.BestSpeed)
+		} else {
+			fw.Reset(writerFunc(w.buffer))
+		}
+		w.flate = fw
+	}
+	return w, nil
+}
+
+// Close closes the connection with the close code and reason. It sends a close frame and waits
+// for the client to acknowledge it before closing the underlying connection. Calling Close more
+// than once, or after the client closed the connection, has no effect.
+func (c *WebSocketConn) Close(code CloseCode, reason string) error {
+	if code != CloseNoStatusReceived && !code.valid() {
+		panic(fmt.Sprintf("invalid websocket close code %d", code))
+	}
+
+	err := c.writeClose(code, reason)
+	timer := time.NewTimer(closeTimeout)
+	defer timer.Stop()
+	select {
+	case <-c.readDone:
+	case <-timer.C:
+	}
+	c.closeConn()
+	return err
+}
+
+// readLoop receives messages until the connection is closed.
+func (c *WebSocketConn) readLoop() {
+	// The connection is closed after readers can see why, so readers using the context of the
+	// connection receive the reason rather than context.Canceled.
+	defer c.closeConn()
+	defer close(c.readDone)
+	for {
+		typ, data, err := c.readMessage()
+		if err != nil {
+			c.readErr = c.fail(err)
+			return
+		}
+		c.received.Add(1)
+		c.conf.metrics.wsMessages.Add(c.ctx, 1, c.conf.attrs,
+			metric.WithAttributes(attribute.String("network.io.direction", "receive")))
+
+		// The read loop never waits for the handler, so control frames are still handled while the
+		// handler isn't reading.
+		select {
+		case <-c.closing:
+			// The remaining messages are discarded while waiting for the client to acknowledge
+			// the close frame.
+		case c.messages <- wsMessage{typ: typ, data: data}:
+		default:
+			c.dropped.Add(1)
+		}
+	}
+}
+
+// fail ends the connection after reading from it failed, returning the error reported to readers.
+func (c *WebSocketConn) fail(err error) error {
+	var closeErr *CloseError
+	if errors.As(err, &closeErr) {
+		// Either the client closed the connection, and its close frame is echoed, or it violated
+		// the protocol, and the close frame tells it why.
+		_ = c.writeClose(closeErr.Code, closeErr.Reason)
+	} else if c.ctx.Err() != nil {
+		closeErr = c.closedError()
+	} else {
+		closeErr = &CloseError{Code: CloseAbnormalClosure, Reason: err.Error()}
+	}
+	return closeErr
+}
+
+// readMessage reads the frames of the next message, handling the control frames in between.
+func (c *WebSocketConn) readMessage() (MessageType, []byte, error) {
+	var (
+		typ        MessageType
+		compressed bool
+		data       []byte
+		started    bool
+	)
+	limit := c.readLimit.Load()
+
+	for {
+		h, err := c.readFrameHeader()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if h.opcode >= opClose {
+			payload, err := c.readPayload(h, nil)
+			if err != nil {
+				return 0, nil, err
+			}
+			if err := c.handleControl(h.opcode, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		}
+
+		switch {
+		case h.opcode == opContinuation && !started:
+			return 0, nil, protocolError("unexpected continuation frame")
+		case h.opcode != opContinuation && started:
+			return 0, nil, protocolError("expected continuation frame")
+		case h.opcode == opText || h.opcode == opBinary:
+			typ, compressed, started = MessageType(h.opcode), h.rsv1, true
+		case h.opcode != opContinuation:
+			return 0, nil, protocolError(fmt.Sprintf("unknown opcode %d", h.opcode))
+		}
+		if h.rsv1 && (h.opcode == opContinuation || !c.conf.compress) {
+			return 0, nil, protocolError("unexpected compressed frame")
+		}
+
+		if limit > 0 && int64(len(data))+h.length > limit {
+			return 0, nil, &CloseError{Code: CloseMessageTooBig,
+				Reason: fmt.Sprintf("message exceeds %d bytes", limit)}
+		}
+		if data, err = c.readPayload(h, data); err != nil {
+			return 0, nil, err
+		}
+		if h.fin {
+			break
+		}
+	}
+
+	if compressed {
+		var err error
+		if data, err = inflate(data, limit); err != nil {
+			return 0, nil, err
+		}
+	}
+	if typ == TextMessage && !utf8.Valid(data) {
+		return 0, nil, &CloseError{Code: CloseInvalidFramePayloadData, Reason: "invalid UTF-8"}
+	}
+	return typ, data, nil
+}
+
+type frameHeader struct {
+	fin    bool
+	rsv1   bool
+	opcode byte
+	length int64
+	mask   [4]byte
+}
+
+func (c *WebSocketConn) readFrameHeader() (frameHeader, error) {
+	var (
+		h frameHeader
+		b [8]byte
+	)
+	c.extendReadDeadline()
+	if _, err := io.ReadFull(c.br, b[:2]); err != nil {
+		return h, err
+	}
+
+	h.fin = b[0]&0x80 != 0
+	h.rsv1 = b[0]&0x40 != 0
+	h.opcode = b[0] & 0x0f
+	if b[0]&0x30 != 0 {
+		return h, protocolError("reserved bits set")
+	}
+	if b[1]&0x80 == 0 {
+		return h, protocolError("frames sent by clients must be masked")
+	}
+
+	switch n := b[1] & 0x7f; n {
+	case 126:
+		if _, err := io.ReadFull(c.br, b[:2]); err != nil {
+			return h, err
+		}
+		h.length = int64(binary.BigEndian.Uint16(b[:2]))
+	case 127:
+		if _, err := io.ReadFull(c.br, b[:8]); err != nil {
+			return h, err
+		}
+		length := binary.BigEndian.Uint64(b[:8])
+		if length>>63 != 0 {
+			return h, protocolError("invalid payload length")
+		}
+		h.length = int64(length)
+	default:
+		h.length = int64(n)
+	}
+
+	if _, err := io.ReadFull(c.br, h.mask[:]); err != nil {
+		return h, err
+	}
+
+	if h.opcode >= opClose {
+		switch {
+		case !h.fin:
+			return h, protocolError("fragmented control frame")
+		case h.length > maxControlPayload:
+			return h, protocolError("control frame too large")
+		case h.rsv1:
+			return h, protocolError("compressed control frame")
+		}
+	}
+	return h, nil
+}
+
+// readPayload reads and unmasks the payload of the frame, appending it to buf.
+func (c *WebSocketConn) readPayload(h frameHeader, buf []byte) ([]byte, error) {
+	c.extendReadDeadline()
+	n := len(buf)
+	if free := int64(cap(buf) - n); free < h.length {
+		buf = append(buf[:cap(buf)], make([]byte, h.length-free)...)
+	}
+	buf = buf[:n+int(h.length)]
+	if _, err := io.ReadFull(c.br, buf[n:]); err != nil {
+		return nil, err
+	}
+	for i := range buf[n:] {
+		buf[n+i] ^= h.mask[i%4]
+	}
+	return buf, nil
+}
+
+// extendReadDeadline gives the client until twice the ping interval to send the next frame. While
+// the client is responsive, it answers the pings sent in the meantime.
+func (c *WebSocketConn) extendReadDeadline() {
+	if c.conf.pingInterval > 0 {
+		_ = c.conn.SetReadDeadline(time.Now().Add(2 * c.conf.pingInterval))
+	}
+}
+
+func (c *WebSocketConn) handleControl(opcode byte, payload []byte) error {
+	switch opcode {
+	case opPing:
+		return c.writeFrame(c.ctx, true, false, opPong, payload)
+	case opPong:
+		return nil
+	case opClose:
+		if len(payload) == 0 {
+			return &CloseError{Code: CloseNoStatusReceived}
+		}
+		if len(payload) == 1 {
+			return protocolError("invalid close frame")
+		}
+		code := CloseCode(binary.BigEndian.Uint16(payload))
+		if !code.valid() {
+			return protocolError(fmt.Sprintf("invalid close code %d", code))
+		}
+		if !utf8.Valid(payload[2:]) {
+			return &CloseError{Code: CloseInvalidFramePayloadData, Reason: "invalid UTF-8"}
+		}
+		return &CloseError{Code: code, Reason: string(payload[2:])}
+	default:
+		return protocolError(fmt.Sprintf("unknown opcode %d", opcode))
+	}
+}
+
+// pingLoop pings the client periodically until the connection is closed.
+func (c *WebSocketConn) pingLoop() {
+	ticker := time.NewTicker(c.conf.pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.writeFrame(c.ctx, true, false, opPing, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeClose sends the close frame unless it was already sent.
+func (c *WebSocketConn) writeClose(code CloseCode, reason string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		return nil
+	}
+	c.closeSent = true
+	close(c.closing)
+
+	var payload []byte
+	if code != CloseNoStatusReceived {
+		// The reason is truncated to fit the control frame without splitting a UTF-8 sequence.
+		if len(reason) > maxControlPayload-2 {
+			reason = reason[:maxControlPayload-2]
+			for !utf8.ValidString(reason) {
+				reason = reason[:len(reason)-1]
+			}
+		}
+		payload = binary.BigEndian.AppendUint16(make([]byte, 0, 2+len(reason)), uint16(code))
+		payload = append(payload, reason...)
+	}
+	if err := c.writeFrameLocked(context.WithoutCancel(c.ctx), true, false, opClose, payload); err != nil {
+		return err
+	}
+	c.code.Store(uint32(code))
+	return nil
+}
+
+// writeFrame sends a frame. Once the close frame is sent, data frames fail with a *CloseError and
+// control frames are discarded.
+func (c *WebSocketConn) writeFrame(ctx context.Context, fin, compressed bool, opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closeSent {
+		if opcode >= opClose {
+			return nil
+		}
+		return c.closedError()
+	}
+	return c.writeFrameLocked(ctx, fin, compressed, opcode, payload)
+}
+
+func (c *WebSocketConn) writeFrameLocked(ctx context.Context, fin, compressed bool, opcode byte, payload []byte) error {
+	var deadline time.Time
+	if c.conf.writeTimeout > 0 {
+		deadline = time.Now().Add(c.conf.writeTimeout)
+	}
+	if d, ok := ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+	}
+	_ = c.conn.SetWriteDeadline(deadline)
+
+	// Moving the deadline to the past interrupts the write when ctx is done.
+	stop := context.AfterFunc(ctx, func() {
+		_ = c.conn.SetWriteDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	b0 := opcode
+	if fin {
+		b0 |= 0x80
+	}
+	if compressed {
+		b0 |= 0x40
+	}
+	hdr := append(c.hdr[:0], b0)
+	switch n := len(payload); {
+	case n <= 125:
+		hdr = append(hdr, byte(n))
+	case n <= 0xffff:
+		hdr = binary.BigEndian.AppendUint16(append(hdr, 126), uint16(n))
+	default:
+		hdr = binary.BigEndian.AppendUint64(append(hdr, 127), uint64(n))
+	}
+
+	_, _ = c.bw.Write(hdr)
+	_, _ = c.bw.Write(payload)
+	if err := c.bw.Flush(); err != nil {
+		// The client cannot make sense of the stream after a partially written frame.
+		c.closeConn()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &CloseError{Code: CloseAbnormalClosure, Reason: err.Error()}
+	}
+	return nil
+}
+
+// closeConn closes the underlying connection.
+func (c *WebSocketConn) closeConn() {
+	c.closeOnce.Do(func() {
+		c.cancel()
+		_ = c.conn.Close()
+	})
+}
+
+// closeCode returns the code of the close frame sent, or CloseAbnormalClosure if the connection
+// was closed without one.
+func (c *WebSocketConn) closeCode() CloseCode {
+	if code := CloseCode(c.code.Load()); code != 0 {
+		return code
+	}
+	return CloseAbnormalClosure
+}
+
+// closedError returns the error reported for operations on the closed connection.
+func (c *WebSocketConn) closedError() *CloseError {
+	return &CloseError{Code: c.closeCode()}
+}
+
+// messageWriter writes a message as a sequence of frames.
+type messageWriter struct {
+	c          *WebSocketConn
+	ctx        context.Context
+	opcode     byte
+	compressed bool
+	flate      *flate.Writer
+	buf        []byte
+	err        error
+	closed     bool
+}
+
+func (w *messageWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("websocket: write to closed message writer")
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	if w.flate != nil {
+		return w.flate.Write(p)
+	}
+	return w.buffer(p)
+}
+
+// buffer buffers the (compressed) content of the message, sending full frames as they become
+// available.
+func (w *messageWriter) buffer(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+
+	// The last 4 bytes of compressed messages are held back, since the end of the sync flush
+	// isn't sent.
+	holdback := 0
+	if w.flate != nil {
+		holdback = 4
+	}
+	for w.err == nil && len(w.buf)-holdback > maxFramePayload {
+		w.writeFrame(false, w.buf[:maxFramePayload])
+		w.buf = w.buf[:copy(w.buf, w.buf[maxFramePayload:])]
+	}
+	return len(p), w.err
+}
+
+func (w *messageWriter) writeFrame(fin bool, payload []byte) {
+	// Only the first frame of a message indicates it is compressed.
+	first := w.opcode != opContinuation
+	w.err = w.c.writeFrame(w.ctx, fin, w.compressed && first, w.opcode, payload)
+	w.opcode = opContinuation
+}
+
+// Close sends the final frame of the message.
+func (w *messageWriter) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+	defer func() { <-w.c.writeSem }()
+
+	if w.flate != nil {
+		if w.err == nil {
+			if err := w.flate.Flush(); err != nil && w.err == nil {
+				w.err = err
+			}
+		}
+		flateWriters.Put(w.flate)
+		w.flate = nil
+		w.buf = bytes.TrimSuffix(w.buf, deflateTail[:4])
+	}
+	if w.err != nil {
+		return w.err
+	}
+
+	w.writeFrame(true, w.buf)
+	if w.err != nil {
+		return w.err
+	}
+	w.c.sent.Add(1)
+	w.c.conf.metrics.wsMessages.Add(w.c.ctx, 1, w.c.conf.attrs,
+		metric.WithAttributes(attribute.String("network.io.direction", "transmit")))
+	return nil
+}
+
+// inflate decompresses a message compressed with permessage-deflate, failing if it exceeds the
+// limit.
+func inflate(data []byte, limit int64) ([]byte, error) {
+	src := io.MultiReader(bytes.NewReader(data), bytes.NewReader(deflateTail))
+	fr, _ := flateReaders.Get().(io.ReadCloser)
+	if fr == nil {
+		fr = flate.NewReader(src)
+	} else {
+		_ = fr.(flate.Resetter).Reset(src, nil)
+	}
+	defer flateReaders.Put(fr)
+
+	r := io.Reader(fr)
+	if limit > 0 {
+		r = io.LimitReader(fr, limit+1)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return nil, &CloseError{Code: CloseInvalidFramePayloadData, Reason: "invalid compressed data"}
+	}
+	if limit > 0 && int64(len(out)) > limit {
+		return nil, &CloseError{Code: CloseMessageTooBig, Reason: fmt.Sprintf("message exceeds %d bytes", limit)}
+	}
+	return out, nil
+}
+
+// writerFunc adapts a function to an io.Writer.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
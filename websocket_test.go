@@ -0,0 +1,743 @@
+package yuna
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// testFrame is a frame sent by the test client or received from the server.
+type testFrame struct {
+	fin     bool
+	rsv1    bool
+	opcode  byte
+	payload []byte
+	// unmasked sends the frame without a mask, which clients aren't allowed to.
+	unmasked bool
+}
+
+func (f testFrame) bytes() []byte {
+	b0 := f.opcode
+	if f.fin {
+		b0 |= 0x80
+	}
+	if f.rsv1 {
+		b0 |= 0x40
+	}
+	b := []byte{b0}
+
+	maskBit := byte(0x80)
+	if f.unmasked {
+		maskBit = 0
+	}
+	switch n := len(f.payload); {
+	case n <= 125:
+		b = append(b, maskBit|byte(n))
+	case n <= 0xffff:
+		b = binary.BigEndian.AppendUint16(append(b, maskBit|126), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint64(append(b, maskBit|127), uint64(n))
+	}
+	if f.unmasked {
+		return append(b, f.payload...)
+	}
+
+	mask := [4]byte{0x12, 0x34, 0x56, 0x78}
+	b = append(b, mask[:]...)
+	for i, c := range f.payload {
+		b = append(b, c^mask[i%4])
+	}
+	return b
+}
+
+func textFrame(s string) testFrame {
+	return testFrame{fin: true, opcode: opText, payload: []byte(s)}
+}
+
+func closeFrame(code CloseCode, reason string) testFrame {
+	return testFrame{fin: true, opcode: opClose,
+		payload: append(binary.BigEndian.AppendUint16(nil, uint16(code)), reason...)}
+}
+
+// deflatePayload compresses the payload as permessage-deflate does, removing the end of the sync flush.
+func deflatePayload(t *testing.T, payload []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	fw, _ := flate.NewWriter(&buf, flate.BestSpeed)
+	if _, err := fw.Write(payload); err != nil {
+		t.Fatal(err)
+	}
+	if err := fw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.TrimSuffix(buf.Bytes(), deflateTail[:4])
+}
+
+// readTestFrame reads a frame sent by the server, which must not be masked.
+func readTestFrame(r *bufio.Reader) (testFrame, error) {
+	var f testFrame
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:2]); err != nil {
+		return f, err
+	}
+	f.fin = b[0]&0x80 != 0
+	f.rsv1 = b[0]&0x40 != 0
+	f.opcode = b[0] & 0x0f
+	if b[1]&0x80 != 0 {
+		return f, errors.New("server frame is masked")
+	}
+
+	length := uint64(b[1] & 0x7f)
+	switch length {
+	case 126:
+		if _, err := io.ReadFull(r, b[:2]); err != nil {
+			return f, err
+		}
+		length = uint64(binary.BigEndian.Uint16(b[:2]))
+	case 127:
+		if _, err := io.ReadFull(r, b[:8]); err != nil {
+			return f, err
+		}
+		length = binary.BigEndian.Uint64(b[:8])
+	}
+	f.payload = make([]byte, length)
+	_, err := io.ReadFull(r, f.payload)
+	return f, err
+}
+
+// testClient is the client end of a WebSocketConn connected through net.Pipe. Frames sent by the
+// server are read in the background, since writes to a pipe block until they are read.
+type testClient struct {
+	t      *testing.T
+	conn   net.Conn
+	frames chan testFrame
+}
+
+func newTestConn(t *testing.T, conf webSocketConnConfig) (*WebSocketConn, *testClient) {
+	t.Helper()
+	server, client := net.Pipe()
+	if conf.metrics == nil {
+		conf.metrics = newResponderMetrics(noop.NewMeterProvider())
+	}
+	if conf.codec == nil {
+		conf.codec = JSONCodec{}
+	}
+	conn := newWebSocketConn(context.Background(), server, bufio.NewReader(server), bufio.NewWriter(server), conf)
+
+	c := &testClient{t: t, conn: client, frames: make(chan testFrame, 64)}
+	go func() {
+		defer close(c.frames)
+		br := bufio.NewReader(client)
+		for {
+			f, err := readTestFrame(br)
+			if err != nil {
+				return
+			}
+			c.frames <- f
+		}
+	}()
+	t.Cleanup(func() {
+		_ = client.Close()
+		conn.closeConn()
+	})
+	return conn, c
+}
+
+// send writes the frames in the background, since the server may stop reading before all of them
+// are written.
+func (c *testClient) send(frames ...testFrame) {
+	var b []byte
+	for _, f := range frames {
+		b = append(b, f.bytes()...)
+	}
+	go func() {
+		_, _ = c.conn.Write(b)
+	}()
+}
+
+// next returns the next frame sent by the server, skipping pings.
+func (c *testClient) next() testFrame {
+	c.t.Helper()
+	for {
+		select {
+		case f, ok := <-c.frames:
+			if !ok {
+				c.t.Fatal("connection closed before the server sent a frame")
+			}
+			if f.opcode == opPing {
+				continue
+			}
+			return f
+		case <-time.After(2 * time.Second):
+			c.t.Fatal("timed out waiting for a frame from the server")
+		}
+	}
+}
+
+// expectClose waits for the close frame of the server and checks its code.
+func (c *testClient) expectClose(code CloseCode) {
+	c.t.Helper()
+	for {
+		f := c.next()
+		if f.opcode != opClose {
+			continue
+		}
+		if len(f.payload) < 2 {
+			c.t.Fatalf("close frame without code, want %d", code)
+		}
+		if got := CloseCode(binary.BigEndian.Uint16(f.payload)); got != code {
+			c.t.Fatalf("close code = %d, want %d", got, code)
+		}
+		return
+	}
+}
+
+func TestWebSocketConnReadMessage(t *testing.T) {
+	large := strings.Repeat("yuna ", 1000)
+
+	tests := []struct {
+		name      string
+		compress  bool
+		readLimit int64
+		frames    func(t *testing.T) []testFrame
+		want      []string
+		wantType  MessageType
+		wantClose CloseCode
+	}{
+		{
+			name:     "text",
+			frames:   func(*testing.T) []testFrame { return []testFrame{textFrame("hello")} },
+			want:     []string{"hello"},
+			wantType: TextMessage,
+		},
+		{
+			name: "empty",
+			frames: func(*testing.T) []testFrame {
+				return []testFrame{{fin: true, opcode: opBinary}}
+			},
+			want:     []string{""},
+			wantType: BinaryMessage,
+		},
+		{
+			name: "16 bit length",
+			frames: func(*testing.T) []testFrame {
+				return []testFrame{{fin: true, opcode: opBinary, payload: []byte(large)}}
+			},
+			want:     []string{large},
+			wantType: BinaryMessage,
+		},
+		{
+			name: "64 bit length",
+			frames: func(*testing.T) []testFrame {
+				return []testFrame{textFrame(strings.Repeat("x", 70000))}
+			},
+			want:     []string{strings.Repeat("x", 70000)},
+			wantType: TextMessage,
+		},
+		{
+			name: "fragmented with interleaved ping",
+			frames: func(*testing.T) []testFrame {
+				return []testFrame{
+					{opcode: opText, payload: []byte("hel")},
+					{fin: true, opcode: opPing, payload: []byte("p")},
+					{opcode: opContinuation, payload: []byte("lo ")},
+					{fin: true, opcode: opContinuation, payload: []byte("world")},
+				}
+			},
+			want:     []string{"hello world"},
+			wantType: TextMessage,
+		},
+		{
+			name:     "compressed",
+			compress: true,
+			frames: func(t *testing.T) []testFrame {
+				return []testFrame{{fin: true, rsv1: true, opcode: opText, payload: deflatePayload(t, []byte(large))}}
+			},
+			want:     []string{large},
+			wantType: TextMessage,
+		},
+		{
+			name:     "compressed and fragmented",
+			compress: true,
+			frames: func(t *testing.T) []testFrame {
+				payload := deflatePayload(t, []byte(large))
+				return []testFrame{
+					{rsv1: true, opcode: opBinary, payload: payload[:10]},
+					{fin: true, opcode: opContinuation, payload: payload[10:]},
+				}
+			},
+			want:     []string{large},
+			wantType: BinaryMessage,
+		},
+		{
+			name: "unmasked",
+			frames: func(*testing.T) []testFrame {
+				return []testFrame{{fin: true, opcode: opText, payload: []byte("a"), unmasked: true}}
+			},
+			wantClose: CloseProtocolError,
+		},
+		{
+			name: "reserved bits",
+			frames: func(*testing.T) []testFrame {
+				return []testFrame{{fin: true, opcode: opText | 0x20}}
+			},
+			wantClose: CloseProtocolError,
+		},
+		{
+			name: "unknown opcode",
+			frames: func(*testing.T) []testFrame {
+				return []testFrame{{fin: true, opcode: 0x3}}
+			},
+			wantClose: CloseProtocolError,
+		},
+		{
+			name: "unexpected continuation",
+			frames: func(*testing.T) []testFrame {
+				return []testFrame{{fin: true, opcode: opContinuation, payload: []byte("a")}}
+			},
+			wantClose: CloseProtocolError,
+		},
+		{
+			name: "missing continuation",
+			frames: func(*testing.T) []testFrame {
+				return []testFrame{{opcode: opText, payload: []byte("a")}, textFrame("b")}
+			},
+			wantClose: CloseProtocolError,
+		},
+		{
+			name: "fragmented control frame",
+			frames: func(*testing.T) []testFrame {
+				return []testFrame{{opcode: opPing}}
+			},
+			wantClose: CloseProtocolError,
+		},
+		{
+			name: "control frame too large",
+			frames: func(*testing.T) []testFrame {
+				return []testFrame{{fin: true, opcode: opPing, payload: make([]byte, 126)}}
+			},
+			wantClose: CloseProtocolError,
+		},
+		{
+			name: "compressed without negotiation",
+			frames: func(t *testing.T) []testFrame {
+				return []testFrame{{fin: true, rsv1: true, opcode: opText, payload: deflatePayload(t, []byte("a"))}}
+			},
+			wantClose: CloseProtocolError,
+		},
+		{
+			name: "invalid UTF-8",
+			frames: func(*testing.T) []testFrame {
+				return []testFrame{{fin: true, opcode: opText, payload: []byte{0xff, 0xfe}}}
+			},
+			wantClose: CloseInvalidFramePayloadData,
+		},
+		{
+			name:      "exceeds read limit",
+			readLimit: 10,
+			frames:    func(*testing.T) []testFrame { return []testFrame{textFrame("more than ten bytes")} },
+			wantClose: CloseMessageTooBig,
+		},
+		{
+			name:      "fragments exceed read limit",
+			readLimit: 10,
+			frames: func(*testing.T) []testFrame {
+				return []testFrame{
+					{opcode: opText, payload: []byte("123456")},
+					{fin: true, opcode: opContinuation, payload: []byte("789012")},
+				}
+			},
+			wantClose: CloseMessageTooBig,
+		},
+		{
+			name:      "decompressed message exceeds read limit",
+			compress:  true,
+			readLimit: 100,
+			frames: func(t *testing.T) []testFrame {
+				return []testFrame{{fin: true, rsv1: true, opcode: opText, payload: deflatePayload(t, []byte(large))}}
+			},
+			wantClose: CloseMessageTooBig,
+		},
+		{
+			name: "close",
+			frames: func(*testing.T) []testFrame {
+				return []testFrame{closeFrame(CloseGoingAway, "bye")}
+			},
+			wantClose: CloseGoingAway,
+		},
+		{
+			name: "close with invalid code",
+			frames: func(*testing.T) []testFrame {
+				return []testFrame{closeFrame(CloseNoStatusReceived, "")}
+			},
+			wantClose: CloseProtocolError,
+		},
+		{
+			name: "close with one byte payload",
+			frames: func(*testing.T) []testFrame {
+				return []testFrame{{fin: true, opcode: opClose, payload: []byte{0x03}}}
+			},
+			wantClose: CloseProtocolError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, client := newTestConn(t, webSocketConnConfig{compress: tt.compress, readLimit: tt.readLimit})
+			client.send(tt.frames(t)...)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			for _, want := range tt.want {
+				typ, data, err := conn.ReadMessage(ctx)
+				if err != nil {
+					t.Fatalf("ReadMessage() error = %v", err)
+				}
+				if typ != tt.wantType || string(data) != want {
+					t.Fatalf("ReadMessage() = %d, %.20q, want %d, %.20q", typ, data, tt.wantType, want)
+				}
+			}
+			if tt.wantClose == 0 {
+				return
+			}
+
+			_, _, err := conn.ReadMessage(ctx)
+			var closeErr *CloseError
+			if !errors.As(err, &closeErr) || closeErr.Code != tt.wantClose {
+				t.Fatalf("ReadMessage() error = %v, want close code %d", err, tt.wantClose)
+			}
+			client.expectClose(tt.wantClose)
+		})
+	}
+}
+
+func TestWebSocketConnAnswersPing(t *testing.T) {
+	_, client := newTestConn(t, webSocketConnConfig{})
+	client.send(testFrame{fin: true, opcode: opPing, payload: []byte("are you there")})
+
+	f := client.next()
+	if f.opcode != opPong || string(f.payload) != "are you there" {
+		t.Fatalf("got opcode %d payload %q, want pong echoing the ping", f.opcode, f.payload)
+	}
+}
+
+func TestWebSocketConnHandlesControlFramesWhileNotReading(t *testing.T) {
+	conn, client := newTestConn(t, webSocketConnConfig{})
+
+	// More messages than are queued, none of which are read, followed by a ping and a close frame.
+	frames := make([]testFrame, 0, messageQueueSize+4)
+	for range messageQueueSize + 2 {
+		frames = append(frames, textFrame("ignored"))
+	}
+	frames = append(frames, testFrame{fin: true, opcode: opPing}, closeFrame(CloseNormalClosure, ""))
+	client.send(frames...)
+
+	if f := client.next(); f.opcode != opPong {
+		t.Fatalf("got opcode %d, want pong", f.opcode)
+	}
+	client.expectClose(CloseNormalClosure)
+
+	select {
+	case <-conn.Context().Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("connection not closed after the close handshake")
+	}
+	if got := conn.dropped.Load(); got != 2 {
+		t.Errorf("dropped = %d, want 2", got)
+	}
+
+	// The queued messages are still delivered before the close.
+	for range messageQueueSize {
+		if _, data, err := conn.ReadMessage(context.Background()); err != nil || string(data) != "ignored" {
+			t.Fatalf("ReadMessage() = %q, %v, want queued message", data, err)
+		}
+	}
+	var closeErr *CloseError
+	if _, _, err := conn.ReadMessage(context.Background()); !errors.As(err, &closeErr) {
+		t.Fatalf("ReadMessage() error = %v, want *CloseError", err)
+	}
+}
+
+func TestWebSocketConnWriteMessage(t *testing.T) {
+	large := strings.Repeat("yuna ", 20000)
+
+	tests := []struct {
+		name          string
+		compress      bool
+		typ           MessageType
+		payload       string
+		wantFragments int
+		wantRSV1      bool
+	}{
+		{name: "text", typ: TextMessage, payload: "hello", wantFragments: 1},
+		{name: "binary", typ: BinaryMessage, payload: "\x00\x01", wantFragments: 1},
+		{name: "fragmented", typ: TextMessage, payload: large, wantFragments: 4},
+		{name: "small message not compressed", compress: true, typ: TextMessage, payload: "hello", wantFragments: 1},
+		{name: "compressed", compress: true, typ: TextMessage, payload: large, wantFragments: 1, wantRSV1: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, client := newTestConn(t, webSocketConnConfig{compress: tt.compress})
+			go func() {
+				_ = conn.WriteMessage(context.Background(), tt.typ, []byte(tt.payload))
+			}()
+
+			var (
+				payload   []byte
+				fragments int
+				first     testFrame
+			)
+			for {
+				f := client.next()
+				if fragments == 0 {
+					first = f
+				} else if f.opcode != opContinuation || f.rsv1 {
+					t.Fatalf("fragment %d has opcode %d rsv1 %t, want continuation", fragments, f.opcode, f.rsv1)
+				}
+				fragments++
+				payload = append(payload, f.payload...)
+				if f.fin {
+					break
+				}
+			}
+
+			if first.opcode != byte(tt.typ) || first.rsv1 != tt.wantRSV1 {
+				t.Fatalf("first frame has opcode %d rsv1 %t, want %d %t", first.opcode, first.rsv1, tt.typ, tt.wantRSV1)
+			}
+			if fragments != tt.wantFragments {
+				t.Errorf("fragments = %d, want %d", fragments, tt.wantFragments)
+			}
+			if first.rsv1 {
+				var err error
+				if payload, err = inflate(payload, 0); err != nil {
+					t.Fatalf("inflate() error = %v", err)
+				}
+			}
+			if string(payload) != tt.payload {
+				t.Errorf("payload = %.20q, want %.20q", payload, tt.payload)
+			}
+		})
+	}
+}
+
+func TestWebSocketConnClose(t *testing.T) {
+	conn, client := newTestConn(t, webSocketConnConfig{})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- conn.Close(CloseGoingAway, "shutting down")
+	}()
+	f := client.next()
+	if f.opcode != opClose || string(f.payload[2:]) != "shutting down" ||
+		CloseCode(binary.BigEndian.Uint16(f.payload)) != CloseGoingAway {
+		t.Fatalf("got opcode %d payload %q, want close frame 1001", f.opcode, f.payload)
+	}
+
+	// The client acknowledges the close frame, which ends the handshake.
+	client.send(closeFrame(CloseGoingAway, ""))
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close() didn't return after the client acknowledged the close frame")
+	}
+
+	if err := conn.WriteMessage(context.Background(), TextMessage, []byte("late")); err == nil {
+		t.Fatal("WriteMessage() after Close() succeeded")
+	}
+	if got := conn.closeCode(); got != CloseGoingAway {
+		t.Errorf("closeCode() = %d, want %d", got, CloseGoingAway)
+	}
+}
+
+func TestWebSocketHandshake(t *testing.T) {
+	app := New()
+	app.Get("/ws", func(*Request) Responder {
+		return WebSocket(func(conn *WebSocketConn) error {
+			return conn.WriteMessage(conn.Context(), TextMessage, []byte(conn.Subprotocol()))
+		}).Subprotocols("v2", "v1").Compression(true)
+	})
+	server := httptest.NewServer(app)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	valid := func() http.Header {
+		h := http.Header{}
+		h.Set(HeaderConnection, "keep-alive, Upgrade")
+		h.Set(HeaderUpgrade, "websocket")
+		h.Set(HeaderSecWebSocketVersion, "13")
+		h.Set(HeaderSecWebSocketKey, "dGhlIHNhbXBsZSBub25jZQ==")
+		h.Set(HeaderSecWebSocketProtocol, "v1, v2")
+		return h
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		header         func(h http.Header)
+		wantStatus     int
+		wantProtocol   string
+		wantExtensions bool
+	}{
+		{
+			name:         "valid",
+			header:       func(http.Header) {},
+			wantStatus:   http.StatusSwitchingProtocols,
+			wantProtocol: "v2",
+		},
+		{
+			name: "same origin",
+			header: func(h http.Header) {
+				h.Set(HeaderOrigin, "http://"+host)
+				h.Set(HeaderSecWebSocketProtocol, "v1")
+			},
+			wantStatus:   http.StatusSwitchingProtocols,
+			wantProtocol: "v1",
+		},
+		{
+			name: "compression",
+			header: func(h http.Header) {
+				h.Set(HeaderSecWebSocketExtensions, "permessage-deflate; client_max_window_bits")
+			},
+			wantStatus:     http.StatusSwitchingProtocols,
+			wantProtocol:   "v2",
+			wantExtensions: true,
+		},
+		{
+			name: "compression with restricted server window",
+			header: func(h http.Header) {
+				h.Set(HeaderSecWebSocketExtensions, "permessage-deflate; server_max_window_bits=10")
+			},
+			wantStatus:   http.StatusSwitchingProtocols,
+			wantProtocol: "v2",
+		},
+		{
+			name:       "not GET",
+			method:     http.MethodPost,
+			header:     func(http.Header) {},
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "missing upgrade",
+			header:     func(h http.Header) { h.Del(HeaderUpgrade) },
+			wantStatus: http.StatusUpgradeRequired,
+		},
+		{
+			name:       "unsupported version",
+			header:     func(h http.Header) { h.Set(HeaderSecWebSocketVersion, "8") },
+			wantStatus: http.StatusUpgradeRequired,
+		},
+		{
+			name:       "invalid key",
+			header:     func(h http.Header) { h.Set(HeaderSecWebSocketKey, "c2hvcnQ=") },
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "cross origin",
+			header:     func(h http.Header) { h.Set(HeaderOrigin, "https://evil.example.com") },
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "unsupported subprotocol",
+			header:     func(h http.Header) { h.Set(HeaderSecWebSocketProtocol, "v3") },
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req, err := http.NewRequest(method, server.URL+"/ws", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header = valid()
+			tt.header(req.Header)
+
+			nc, err := net.Dial("tcp", host)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer nc.Close()
+			_ = nc.SetDeadline(time.Now().Add(5 * time.Second))
+			if err := req.Write(nc); err != nil {
+				t.Fatal(err)
+			}
+			br := bufio.NewReader(nc)
+			resp, err := http.ReadResponse(br, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusSwitchingProtocols {
+				return
+			}
+
+			// The example of RFC 6455 section 1.3.
+			if got := resp.Header.Get(HeaderSecWebSocketAccept); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+				t.Errorf("Sec-WebSocket-Accept = %q", got)
+			}
+			if got := resp.Header.Get(HeaderSecWebSocketProtocol); got != tt.wantProtocol {
+				t.Errorf("Sec-WebSocket-Protocol = %q, want %q", got, tt.wantProtocol)
+			}
+			if got := resp.Header.Get(HeaderSecWebSocketExtensions) != ""; got != tt.wantExtensions {
+				t.Errorf("Sec-WebSocket-Extensions = %q, want extensions %t",
+					resp.Header.Get(HeaderSecWebSocketExtensions), tt.wantExtensions)
+			}
+
+			f, err := readTestFrame(br)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.opcode != opText || string(f.payload) != tt.wantProtocol {
+				t.Errorf("message = %d %q, want the subprotocol", f.opcode, f.payload)
+			}
+		})
+	}
+}
+
+func TestDeflateOffered(t *testing.T) {
+	tests := []struct {
+		extensions string
+		want       bool
+	}{
+		{"", false},
+		{"permessage-deflate", true},
+		{"x-webkit-deflate-frame, permessage-deflate; client_max_window_bits", true},
+		{"permessage-deflate; server_no_context_takeover; client_no_context_takeover", true},
+		{`permessage-deflate; server_max_window_bits="15"`, true},
+		{"permessage-deflate; server_max_window_bits=10", false},
+		{"permessage-deflate; server_max_window_bits=10, permessage-deflate", true},
+		{"permessage-deflate; unknown_param", false},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		if tt.extensions != "" {
+			h.Set(HeaderSecWebSocketExtensions, tt.extensions)
+		}
+		if got := deflateOffered(h); got != tt.want {
+			t.Errorf("deflateOffered(%q) = %t, want %t", tt.extensions, got, tt.want)
+		}
+	}
+}
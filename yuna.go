@@ -2,12 +2,17 @@ package yuna
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
+	"net"
 	"net/http"
 	_ "net/http/pprof"
+	"os/signal"
 	"runtime/debug"
+	"sync"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -19,12 +24,23 @@ import (
 
 type Yuna struct {
 	router        chi.Router
+	mux           *Mux
 	server        *http.Server
 	opServer      *http.Server
 	config        *config
 	healthHandler *healthcheckHandler
+	lifecycle     *lifecycle
 	logger        *log.Logger
 	startTs       time.Time
+
+	lnMu  sync.Mutex
+	appLn net.Listener
+	opsLn net.Listener
+
+	appCerts *certLoader
+	opsCerts *certLoader
+
+	configSections configSections
 }
 
 func New(opts ...ServerOption) *Yuna {
@@ -39,9 +55,16 @@ func New(opts ...ServerOption) *Yuna {
 		router:        chi.NewRouter(),
 		config:        conf,
 		healthHandler: newHealthcheckHandler(),
+		lifecycle:     newLifecycle(conf.hookTimeout, conf.logger),
 		logger:        conf.logger,
+		startTs:       time.Now(), // Updated when Start/StartTLS/Run is called
+		configSections: configSections{
+			sections: make(map[string]any),
+		},
 	}
 
+	z.mux = newMux(z.router)
+
 	httpServer := &http.Server{
 		Addr:              fmt.Sprintf(":%d", conf.httpPort),
 		Handler:           z,
@@ -51,15 +74,44 @@ func New(opts ...ServerOption) *Yuna {
 		IdleTimeout:       conf.idleTimeout,
 		BaseContext:       conf.baseContext,
 	}
+	configureHTTP2(httpServer, conf)
 	z.server = httpServer
 
 	z.opServer = z.initOpsServer(conf)
 
+	if conf.tls != nil {
+		z.appCerts = newCertLoader("main", conf.tls, conf.logger, conf.meterProvider)
+		z.server.TLSConfig = conf.tls.tlsConfig(z.appCerts)
+	}
+	if conf.clientAuth != tls.NoClientCert || conf.clientCAs != nil {
+		if z.server.TLSConfig == nil {
+			// StartTLS loads the certificate into the TLSConfig so only client authentication needs
+			// to be configured.
+			z.server.TLSConfig = &tls.Config{}
+		}
+		z.server.TLSConfig.ClientAuth = conf.clientAuth
+		z.server.TLSConfig.ClientCAs = conf.clientCAs
+		if conf.clientCAs != nil && conf.clientAuth == tls.NoClientCert {
+			z.server.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+	if conf.operationTLS != nil {
+		z.opsCerts = newCertLoader("operations", conf.operationTLS, conf.logger, conf.meterProvider)
+		z.opServer.TLSConfig = conf.operationTLS.tlsConfig(z.opsCerts)
+	}
+
 	// Setup default middleware
+	z.router.Use(withSettings(newSettings(conf)))
 	z.router.Use(recovery())
 	z.router.Use(middleware.Trace(conf.traceProvider, z))
 	z.router.Use(middleware.InstrumentHandler(conf.meterProvider, conf.requestDurationBuckets))
 	z.router.Use(middleware.RequestLogger(conf.logger))
+	if conf.cors != nil {
+		z.router.Use(CORS(*conf.cors))
+	}
+	if conf.maxRequestBodySize > 0 {
+		z.router.Use(limitBody(conf.maxRequestBodySize, false))
+	}
 
 	// Setup global authentication middleware if it was enabled/configured
 	if conf.authenticator != nil {
@@ -97,6 +149,9 @@ func (z *Yuna) initOpsServer(conf *config) *http.Server {
 		_ = json.NewEncoder(w).Encode(buildInfo)
 	})
 
+	opMux.Get("/routes", routesHandler(z.router))
+	opMux.Get("/config", z.configHandler)
+
 	opMux.Get("/uptime", func(w http.ResponseWriter, r *http.Request) {
 		type resp struct {
 			Uptime string    `json:"uptime"`
@@ -176,20 +231,142 @@ func (z *Yuna) initOpsServer(conf *config) *http.Server {
 // separate goroutine. Start begins listening and serving HTTP requests on the main HTTP server,
 // and operations HTTP server. If either server encounters an error Start will return an error and
 // the Yuna instance is no longer usable.
+//
+// The addresses the servers listen on are determined by the configuration, see WithHTTPPort,
+// WithListenAddress, WithUnixSocket, WithSystemdSocketActivation, and WithOperationsListenAddress.
 func (z *Yuna) Start() error {
+	if err := z.lifecycle.start(); err != nil {
+		return fmt.Errorf("server stopped with error: %w", err)
+	}
+	appLn, opsLn, err := z.listen()
+	if err != nil {
+		return fmt.Errorf("server stopped with error: %w", errors.Join(err, z.lifecycle.stop()))
+	}
+	return z.serve(appLn, opsLn, z.serveApp)
+}
+
+// StartTLS begins listening and serving HTTPS requests.
+//
+// StartTLS blocks until the server is stopped or an error occurs. Generally, StartTLS should be called in a
+// separate goroutine. StartTLS begins listening and serving HTTPS requests on the main HTTP server,
+// while the Prometheus HTTP server and optionally pprof HTTP server if enabled, use HTTP instead of HTTPS.
+func (z *Yuna) StartTLS(certFile, keyFile string) error {
+	if err := z.lifecycle.start(); err != nil {
+		return fmt.Errorf("server stopped with error: %w", err)
+	}
+	appLn, opsLn, err := z.listen()
+	if err != nil {
+		return fmt.Errorf("server stopped with error: %w", errors.Join(err, z.lifecycle.stop()))
+	}
+	return z.serve(appLn, opsLn, func(ln net.Listener) error {
+		return z.server.ServeTLS(ln, certFile, keyFile)
+	})
+}
+
+// Serve accepts incoming HTTP connections on the provided listeners. The main HTTP server serves
+// requests on appLn and the operations HTTP server serves requests on opsLn. If opsLn is nil, the
+// operations HTTP server listens on its configured address. If WithTLS or WithOperationsTLS were
+// provided the respective server serves HTTPS.
+//
+// Serve behaves the same as Start, blocking until the server is stopped or an error occurs. Serve
+// takes ownership of the listeners and closes them when the servers are shut down.
+func (z *Yuna) Serve(appLn, opsLn net.Listener) error {
+	return z.serveListeners(appLn, opsLn, z.serveApp)
+}
+
+// ServeTLS is like Serve but the main HTTP server serves HTTPS requests using the provided
+// certificate and key files.
+func (z *Yuna) ServeTLS(appLn, opsLn net.Listener, certFile, keyFile string) error {
+	return z.serveListeners(appLn, opsLn, func(ln net.Listener) error {
+		return z.server.ServeTLS(ln, certFile, keyFile)
+	})
+}
+
+// serveListeners validates the caller provided listeners before serving. If opsLn is nil the
+// operations HTTP server listens on its configured address.
+func (z *Yuna) serveListeners(appLn, opsLn net.Listener, serveApp func(ln net.Listener) error) error {
+	if appLn == nil {
+		return errors.New("server stopped with error: main HTTP server listener cannot be nil")
+	}
+	if err := z.lifecycle.start(); err != nil {
+		_ = appLn.Close()
+		if opsLn != nil {
+			_ = opsLn.Close()
+		}
+		return fmt.Errorf("server stopped with error: %w", err)
+	}
+	if opsLn == nil {
+		var err error
+		opsLn, err = z.listenOps()
+		if err != nil {
+			_ = appLn.Close()
+			err = errors.Join(fmt.Errorf("operations HTTP server: %w", err), z.lifecycle.stop())
+			return fmt.Errorf("server stopped with error: %w", err)
+		}
+	}
+	return z.serve(appLn, opsLn, serveApp)
+}
+
+// serveApp serves the main HTTP server on the listener, using HTTPS if it was configured with WithTLS.
+func (z *Yuna) serveApp(ln net.Listener) error {
+	if z.appCerts != nil {
+		return z.server.ServeTLS(ln, "", "")
+	}
+	return z.server.Serve(ln)
+}
+
+// serveOps serves the operations HTTP server on the listener, using HTTPS if it was configured with
+// WithOperationsTLS.
+func (z *Yuna) serveOps(ln net.Listener) error {
+	if z.opsCerts != nil {
+		return z.opServer.ServeTLS(ln, "", "")
+	}
+	return z.opServer.Serve(ln)
+}
+
+// loadCertificates performs the initial load of the TLS certificates and starts watching them for
+// changes until the context is cancelled.
+func (z *Yuna) loadCertificates(ctx context.Context) error {
+	for _, loader := range []*certLoader{z.appCerts, z.opsCerts} {
+		if loader == nil {
+			continue
+		}
+		if err := loader.Load(); err != nil {
+			return fmt.Errorf("%s HTTP server: %w", loader.server, err)
+		}
+		go loader.Watch(ctx)
+	}
+	return nil
+}
+
+func (z *Yuna) serve(appLn, opsLn net.Listener, serveApp func(ln net.Listener) error) error {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := z.loadCertificates(ctx); err != nil {
+		_ = appLn.Close()
+		_ = opsLn.Close()
+		return fmt.Errorf("server stopped with error: %w", errors.Join(err, z.lifecycle.stop()))
+	}
+
+	z.lnMu.Lock()
+	z.appLn = appLn
+	z.opsLn = opsLn
+	z.lnMu.Unlock()
 
 	z.startTs = time.Now()
 	errs := make(chan error, 2)
 
 	go func() {
-		z.logger.Info(fmt.Sprintf("Starting operations HTTP server on port %d", z.config.operationHTTPPort))
-		err := z.opServer.ListenAndServe()
+		z.logger.Info(fmt.Sprintf("Starting operations HTTP server on %s", opsLn.Addr()))
+		err := z.serveOps(opsLn)
 		errs <- fmt.Errorf("operations HTTP server: %w", err)
 	}()
 
 	go func() {
-		z.logger.Info(fmt.Sprintf("Starting main HTTP server on port %d", z.config.httpPort))
-		err := z.server.ListenAndServe()
+		z.logger.Info(fmt.Sprintf("Starting main HTTP server on %s", appLn.Addr()))
+		err := serveApp(appLn)
 		errs <- fmt.Errorf("main HTTP server: %w", err)
 	}()
 
@@ -197,67 +374,149 @@ func (z *Yuna) Start() error {
 	if !errors.Is(err, http.ErrServerClosed) {
 		_ = z.opServer.Close()
 		_ = z.server.Close()
-		return fmt.Errorf("server stopped with error: %w", err)
+		return fmt.Errorf("server stopped with error: %w", errors.Join(err, z.lifecycle.stop()))
 	}
 
 	err = <-errs
 	if !errors.Is(err, http.ErrServerClosed) {
-		return fmt.Errorf("server stopped with error: %w", err)
+		return fmt.Errorf("server stopped with error: %w", errors.Join(err, z.lifecycle.stop()))
 	}
 
 	return nil
 }
 
-// StartTLS begins listening and serving HTTPS requests.
-//
-// StartTLS blocks until the server is stopped or an error occurs. Generally, StartTLS should be called in a
-// separate goroutine. StartTLS begins listening and serving HTTPS requests on the main HTTP server,
-// while the Prometheus HTTP server and optionally pprof HTTP server if enabled, use HTTP instead of HTTPS.
-func (z *Yuna) StartTLS(certFile, keyFile string) error {
+// Addr returns the address the main HTTP server is listening on, or nil if the server hasn't been
+// started. This is useful to retrieve the actual port when listening on port 0.
+func (z *Yuna) Addr() net.Addr {
+	z.lnMu.Lock()
+	defer z.lnMu.Unlock()
+	if z.appLn == nil {
+		return nil
+	}
+	return z.appLn.Addr()
+}
 
-	z.startTs = time.Now()
-	errs := make(chan error, 2)
+// OperationsAddr returns the address the operations HTTP server is listening on, or nil if the
+// server hasn't been started.
+func (z *Yuna) OperationsAddr() net.Addr {
+	z.lnMu.Lock()
+	defer z.lnMu.Unlock()
+	if z.opsLn == nil {
+		return nil
+	}
+	return z.opsLn.Addr()
+}
 
-	go func() {
-		z.logger.Info(fmt.Sprintf("Starting operations HTTP server on port %d", z.config.operationHTTPPort))
-		err := z.opServer.ListenAndServe()
-		errs <- fmt.Errorf("operations HTTP server: %w", err)
-	}()
+// Run starts the main HTTP server and operations HTTP server and blocks until the provided context
+// is cancelled, a shutdown signal is received, or either server encounters an error.
+//
+// When the context is cancelled or a shutdown signal is received (SIGINT and SIGTERM by default, see
+// WithShutdownSignals), Run performs a coordinated graceful shutdown:
+//
+//  1. The readiness endpoint on the operations server starts reporting DOWN, and keep-alives are
+//     disabled so responses on existing connections are sent with "Connection: close".
+//  2. Run waits for the pre-stop delay (see WithPreStopDelay) so load balancers can stop routing
+//     traffic to the instance.
+//  3. The main HTTP server is shut down, waiting for in-flight requests to complete, followed by the
+//     operations HTTP server. Both are bound by the shutdown timeout (see WithShutdownTimeout).
+//
+// Run returns nil if the servers were shut down cleanly, otherwise it returns the combined errors
+// from starting and shutting down the servers.
+func (z *Yuna) Run(ctx context.Context) error {
+	// signal.NotifyContext relays every signal when none are given, so an empty list disables
+	// signal handling rather than shutting down on any signal.
+	stop := func() {}
+	if len(z.config.shutdownSignals) > 0 {
+		ctx, stop = signal.NotifyContext(ctx, z.config.shutdownSignals...)
+	}
+	defer stop()
 
+	errs := make(chan error, 1)
 	go func() {
-		z.logger.Info(fmt.Sprintf("Starting main HTTP server on port %d", z.config.httpPort))
-		err := z.server.ListenAndServeTLS(certFile, keyFile)
-		errs <- fmt.Errorf("main HTTP server: %w", err)
+		errs <- z.Start()
 	}()
 
-	err := <-errs
-	if !errors.Is(err, http.ErrServerClosed) {
-		_ = z.opServer.Close()
-		_ = z.server.Close()
-		return fmt.Errorf("server stopped with error: %w", err)
+	select {
+	case err := <-errs:
+		// The servers stopped without being asked to, which only happens if they failed to start or
+		// encountered an error while serving.
+		return err
+	case <-ctx.Done():
 	}
 
-	err = <-errs
-	if !errors.Is(err, http.ErrServerClosed) {
-		return fmt.Errorf("server stopped with error: %w", err)
+	// Restore the default signal behavior so a second signal terminates the process immediately if
+	// the graceful shutdown is taking too long.
+	stop()
+
+	z.logger.Info("Shutdown initiated, draining HTTP servers")
+	z.drain()
+
+	if z.config.preStopDelay > 0 {
+		z.logger.Info(fmt.Sprintf("Waiting %s before shutting down HTTP servers", z.config.preStopDelay))
+		time.Sleep(z.config.preStopDelay)
+	}
+
+	shutdownErr := z.Shutdown(z.config.shutdownTimeout)
+	startErr := <-errs
+
+	if err := errors.Join(shutdownErr, startErr); err != nil {
+		return err
 	}
 
+	z.logger.Info("HTTP servers shutdown gracefully")
 	return nil
 }
 
+// drain signals the application is going away. The readiness endpoint begins reporting DOWN and
+// keep-alives are disabled on the main HTTP server so clients establish new connections elsewhere.
+func (z *Yuna) drain() {
+	z.healthHandler.markShuttingDown()
+	z.server.SetKeepAlivesEnabled(false)
+}
+
+// opsShutdownTimeout is the maximum duration Shutdown waits for requests to the operations HTTP
+// server to complete. The operations server is shut down last, so it is given its own grace period
+// rather than whatever remains after the main server drained and the stop hooks ran.
+const opsShutdownTimeout = 5 * time.Second
+
 // Shutdown gracefully shuts down the server without interrupting any active connections and inflight
 // requests. Shutdown accepts a time.Duration which represents the maximum duration to wait for in-flight
 // requests to complete. After the timeout passes, Shutdown will forcefully close all connections impacting
 // any in-flight requests.
+//
+// The main HTTP server is shut down first, then the stop hooks registered with OnStop are run,
+// followed by the operations HTTP server, so that health checks and metrics remain available while
+// in-flight requests are draining. The operations server waits at most the shorter of
+// terminationGraceDuration and five seconds for its requests before its connections are closed.
+// Errors from shutting down either server and from the stop hooks are combined and returned.
 func (z *Yuna) Shutdown(terminationGraceDuration time.Duration) error {
-	ctx, cancel := context.WithTimeout(context.Background(), terminationGraceDuration)
-	defer cancel()
+	z.drain()
 
-	go func() {
-		_ = z.opServer.Shutdown(ctx)
-	}()
+	var errs []error
+	if err := shutdownServer(z.server, terminationGraceDuration); err != nil {
+		errs = append(errs, fmt.Errorf("main HTTP server: %w", err))
+	}
+	if err := z.lifecycle.stop(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := shutdownServer(z.opServer, min(terminationGraceDuration, opsShutdownTimeout)); err != nil {
+		errs = append(errs, fmt.Errorf("operations HTTP server: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// shutdownServer gracefully shuts down the server, closing any connections which are still active
+// once the timeout expires.
+func shutdownServer(server *http.Server, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-	return z.server.Shutdown(ctx)
+	err := server.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		_ = server.Close()
+	}
+	return err
 }
 
 // Close immediately closes all active listeners and all connections.
@@ -273,6 +532,9 @@ func (z *Yuna) Close() error {
 // RegisterOnShutdown registers a function to be called when the server is shutting down. This can be
 // used to gracefully shutdown connections that have undergone ALPN protocol upgrade or that have been
 // hijacked.
+//
+// The function is run in its own goroutine without ordering, timeouts, or error reporting. To
+// release resources such as database pools in a controlled order, use OnStop instead.
 func (z *Yuna) RegisterOnShutdown(f func()) {
 	z.server.RegisterOnShutdown(f)
 }
@@ -284,53 +546,59 @@ func (z *Yuna) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	z.router.ServeHTTP(w, r)
 }
 
+// OperationsHandler returns the http.Handler serving the operations endpoints, such as health checks,
+// metrics, and pprof. This is primarily useful for testing the operations endpoints without starting
+// the operations HTTP server.
+func (z *Yuna) OperationsHandler() http.Handler {
+	return z.opServer.Handler
+}
+
 func (z *Yuna) Use(middleware ...func(http.Handler) http.Handler) {
 	z.router.Use(middleware...)
 }
 
 func (z *Yuna) With(middleware ...func(http.Handler) http.Handler) Router {
-	router := z.router.With(middleware...)
-	return &Mux{r: router}
+	return z.mux.With(middleware...)
 }
 
 func (z *Yuna) Get(pattern string, fn HandlerFunc, middleware ...HttpMiddleware) {
-	z.router.Get(pattern, wrapFn(fn, middleware...))
+	z.mux.Get(pattern, fn, middleware...)
 }
 
 func (z *Yuna) Post(pattern string, fn HandlerFunc, middleware ...HttpMiddleware) {
-	z.router.Post(pattern, wrapFn(fn, middleware...))
+	z.mux.Post(pattern, fn, middleware...)
 }
 
 func (z *Yuna) Put(pattern string, fn HandlerFunc, middleware ...HttpMiddleware) {
-	z.router.Put(pattern, wrapFn(fn, middleware...))
+	z.mux.Put(pattern, fn, middleware...)
 }
 
 func (z *Yuna) Delete(pattern string, fn HandlerFunc, middleware ...HttpMiddleware) {
-	z.router.Delete(pattern, wrapFn(fn, middleware...))
+	z.mux.Delete(pattern, fn, middleware...)
 }
 
 func (z *Yuna) Patch(pattern string, fn HandlerFunc, middleware ...HttpMiddleware) {
-	z.router.Patch(pattern, wrapFn(fn, middleware...))
+	z.mux.Patch(pattern, fn, middleware...)
 }
 
 func (z *Yuna) Options(pattern string, fn HandlerFunc, middleware ...HttpMiddleware) {
-	z.router.Options(pattern, wrapFn(fn, middleware...))
+	z.mux.Options(pattern, fn, middleware...)
 }
 
 func (z *Yuna) Head(pattern string, fn HandlerFunc, middleware ...HttpMiddleware) {
-	z.router.Head(pattern, wrapFn(fn, middleware...))
+	z.mux.Head(pattern, fn, middleware...)
 }
 
 func (z *Yuna) Connect(pattern string, fn HandlerFunc, middleware ...HttpMiddleware) {
-	z.router.Connect(pattern, wrapFn(fn, middleware...))
+	z.mux.Connect(pattern, fn, middleware...)
 }
 
 func (z *Yuna) Trace(pattern string, fn HandlerFunc, middleware ...HttpMiddleware) {
-	z.router.Trace(pattern, wrapFn(fn, middleware...))
+	z.mux.Trace(pattern, fn, middleware...)
 }
 
 func (z *Yuna) Method(method, pattern string, handler Handler, middleware ...HttpMiddleware) {
-	z.router.Method(method, pattern, wrap(handler, middleware...))
+	z.mux.Method(method, pattern, handler, middleware...)
 }
 
 func (z *Yuna) Mount(pattern string, h http.Handler) {
@@ -338,15 +606,16 @@ func (z *Yuna) Mount(pattern string, h http.Handler) {
 }
 
 func (z *Yuna) Route(pattern string, fn func(r Router)) {
-	z.router.Route(pattern, func(r chi.Router) {
-		fn(&Mux{r: r})
-	})
+	z.mux.Route(pattern, fn)
 }
 
 func (z *Yuna) Group(fn func(r Router)) {
-	z.router.Group(func(r chi.Router) {
-		fn(&Mux{r: r})
-	})
+	z.mux.Group(fn)
+}
+
+// Static serves the files of fsys for GET and HEAD requests to paths under prefix, see Mux.Static.
+func (z *Yuna) Static(prefix string, fsys fs.FS, opts ...StaticOption) {
+	z.mux.Static(prefix, fsys, opts...)
 }
 
 func (z *Yuna) Routes() []chi.Route {
@@ -0,0 +1,199 @@
+package yuna
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startApp runs the application with Run until the returned cancel function is called, returning
+// once both servers accept connections.
+func startApp(t *testing.T, app *Yuna) (cancel context.CancelFunc, done <-chan error) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		errs <- app.Run(ctx)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for app.Addr() == nil || app.OperationsAddr() == nil {
+		if time.Now().After(deadline) {
+			cancel()
+			t.Fatal("servers didn't start")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return cancel, errs
+}
+
+func testApp(opts ...ServerOption) *Yuna {
+	return New(append([]ServerOption{
+		WithListenAddress("127.0.0.1:0"),
+		WithOperationsListenAddress("127.0.0.1:0"),
+		WithHealthChecks(),
+		WithShutdownSignals(),
+	}, opts...)...)
+}
+
+func readiness(t *testing.T, app *Yuna) int {
+	t.Helper()
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/healthz/ready", app.OperationsAddr()))
+	if err != nil {
+		t.Errorf("readiness request failed: %v", err)
+		return 0
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestRunShutdownOrder(t *testing.T) {
+	app := testApp(WithPreStopDelay(200*time.Millisecond), WithShutdownTimeout(5*time.Second))
+
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+	record := func(event string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	app.Get("/slow", func(r *Request) Responder {
+		close(entered)
+		<-release
+		record("request completed")
+		return Ok(nil)
+	})
+	app.OnStop("db", func(ctx context.Context) error {
+		// The operations server is still serving while the stop hooks run.
+		if status := readiness(t, app); status != http.StatusServiceUnavailable {
+			t.Errorf("readiness during stop hooks = %d, want 503", status)
+		}
+		record("stop hook")
+		return nil
+	})
+
+	cancel, done := startApp(t, app)
+	if status := readiness(t, app); status != http.StatusOK {
+		t.Fatalf("readiness before shutdown = %d, want 200", status)
+	}
+
+	slow := make(chan error, 1)
+	go func() {
+		resp, err := http.Get(fmt.Sprintf("http://%s/slow", app.Addr()))
+		if err == nil {
+			_ = resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				err = fmt.Errorf("status %d", resp.StatusCode)
+			}
+		}
+		slow <- err
+	}()
+	<-entered
+
+	cancel()
+	// The readiness endpoint reports DOWN during the pre-stop delay, before the servers shut down.
+	time.Sleep(50 * time.Millisecond)
+	if status := readiness(t, app); status != http.StatusServiceUnavailable {
+		t.Errorf("readiness during pre-stop delay = %d, want 503", status)
+	}
+	record("drained")
+	close(release)
+
+	if err := <-slow; err != nil {
+		t.Errorf("in-flight request failed during shutdown: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() didn't return after shutdown")
+	}
+
+	want := []string{"drained", "request completed", "stop hook"}
+	if strings.Join(events, ", ") != strings.Join(want, ", ") {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+	if _, err := http.Get(fmt.Sprintf("http://%s/healthz/live", app.OperationsAddr())); err == nil {
+		t.Error("operations server still serving after Run returned")
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	app := testApp(WithPPROF())
+
+	entered := make(chan struct{})
+	app.Get("/stuck", func(r *Request) Responder {
+		close(entered)
+		<-r.Context().Done()
+		return Ok(nil)
+	})
+
+	// The stop hook starts a request to the operations server that outlives what would remain of the
+	// grace period after the main HTTP server used it up.
+	profiled := make(chan error, 1)
+	app.OnStop("profiler", func(ctx context.Context) error {
+		go func() {
+			resp, err := http.Get(fmt.Sprintf("http://%s/debug/pprof/profile?seconds=1", app.OperationsAddr()))
+			if err == nil {
+				_ = resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					err = fmt.Errorf("status %d", resp.StatusCode)
+				}
+			}
+			profiled <- err
+		}()
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	})
+
+	_, done := startApp(t, app)
+
+	stuck := make(chan error, 1)
+	go func() {
+		resp, err := http.Get(fmt.Sprintf("http://%s/stuck", app.Addr()))
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		stuck <- err
+	}()
+	<-entered
+
+	err := app.Shutdown(2 * time.Second)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "main HTTP server") {
+		t.Errorf("Shutdown() error = %v, want deadline exceeded for the main HTTP server", err)
+	}
+	// The operations server gets its own grace period rather than the expired one of the main server.
+	if err != nil && strings.Contains(err.Error(), "operations HTTP server") {
+		t.Errorf("Shutdown() error = %v, want the operations HTTP server to shut down cleanly", err)
+	}
+	if err := <-profiled; err != nil {
+		t.Errorf("operations request failed during shutdown: %v", err)
+	}
+
+	// Connections still active once the grace period expired are closed.
+	select {
+	case err := <-stuck:
+		if err == nil {
+			t.Error("in-flight request completed, want its connection closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection of in-flight request wasn't closed")
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Run() error = %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+// Package yunatest provides utilities for testing applications built with Yuna.
+//
+// The App harness runs a Yuna application in-process without binding to any ports. Requests are
+// dispatched directly to the main and operations handlers, and the telemetry produced by Yuna is
+// captured in-memory so tests can assert on spans and metrics.
+//
+//	func TestGetUser(t *testing.T) {
+//		app := yunatest.New(t)
+//		app.Yuna().Get("/users/{id}", getUser)
+//
+//		app.Get("/users/{id}").
+//			PathParam("id", "42").
+//			WithPrincipal(admin).
+//			Do().
+//			AssertStatus(http.StatusOK).
+//			AssertJSON(map[string]any{"id": "42"})
+//	}
+package yunatest
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/jkratz55/yuna"
+)
+
+// RequestDurationMetric is the name of the histogram Yuna records the duration of requests to.
+const RequestDurationMetric = "http.server.request.duration"
+
+// App is an in-process test harness for a Yuna application.
+type App struct {
+	t      testing.TB
+	yuna   *yuna.Yuna
+	spans  *tracetest.SpanRecorder
+	reader *sdkmetric.ManualReader
+}
+
+// New creates a Yuna application configured with the provided options and wraps it in a test
+// harness. The application is configured with an in-memory TracerProvider and MeterProvider, which
+// take precedence over any providers passed in opts, and with an authenticator decorator
+// authenticating requests built with RequestBuilder.WithPrincipal with their Principal.
+//
+// The telemetry providers are shut down when the test completes.
+func New(t testing.TB, opts ...yuna.ServerOption) *App {
+	t.Helper()
+
+	spans := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))
+
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	t.Cleanup(func() {
+		_ = tp.Shutdown(context.Background())
+		_ = mp.Shutdown(context.Background())
+	})
+
+	// The options are copied so the telemetry options aren't written to the backing array of the
+	// caller's slice.
+	opts = slices.Concat(opts, []yuna.ServerOption{
+		yuna.WithTraceProvider(tp),
+		yuna.WithMeterProvider(mp),
+		yuna.WithAuthenticatorDecorator(func(authenticator yuna.HttpAuthenticator) yuna.HttpAuthenticator {
+			return &principalAuthenticator{next: authenticator}
+		}),
+	})
+
+	return &App{
+		t:      t,
+		yuna:   yuna.New(opts...),
+		spans:  spans,
+		reader: reader,
+	}
+}
+
+// Yuna returns the Yuna application under test, which is used to register routes, middleware,
+// health checks, etc.
+func (a *App) Yuna() *yuna.Yuna {
+	return a.yuna
+}
+
+// Request creates a request to the main HTTP handler. The path may contain URL parameters in the
+// same format used to register routes, such as /users/{id}, which are replaced using
+// RequestBuilder.PathParam.
+func (a *App) Request(method, path string) *RequestBuilder {
+	return newRequestBuilder(a.t, a.yuna, method, path)
+}
+
+// OpsRequest creates a request to the operations HTTP handler, which serves health checks, metrics,
+// pprof, etc.
+func (a *App) OpsRequest(method, path string) *RequestBuilder {
+	return newRequestBuilder(a.t, a.yuna.OperationsHandler(), method, path)
+}
+
+// Get creates a GET request to the main HTTP handler.
+func (a *App) Get(path string) *RequestBuilder {
+	return a.Request(http.MethodGet, path)
+}
+
+// Head creates a HEAD request to the main HTTP handler.
+func (a *App) Head(path string) *RequestBuilder {
+	return a.Request(http.MethodHead, path)
+}
+
+// Post creates a POST request to the main HTTP handler.
+func (a *App) Post(path string) *RequestBuilder {
+	return a.Request(http.MethodPost, path)
+}
+
+// Put creates a PUT request to the main HTTP handler.
+func (a *App) Put(path string) *RequestBuilder {
+	return a.Request(http.MethodPut, path)
+}
+
+// Patch creates a PATCH request to the main HTTP handler.
+func (a *App) Patch(path string) *RequestBuilder {
+	return a.Request(http.MethodPatch, path)
+}
+
+// Delete creates a DELETE request to the main HTTP handler.
+func (a *App) Delete(path string) *RequestBuilder {
+	return a.Request(http.MethodDelete, path)
+}
+
+// Options creates an OPTIONS request to the main HTTP handler.
+func (a *App) Options(path string) *RequestBuilder {
+	return a.Request(http.MethodOptions, path)
+}
+
+// SpanRecorder returns the recorder capturing the spans produced by the application.
+func (a *App) SpanRecorder() *tracetest.SpanRecorder {
+	return a.spans
+}
+
+// Spans returns the spans that have ended, in the order they ended.
+func (a *App) Spans() []sdktrace.ReadOnlySpan {
+	return a.spans.Ended()
+}
+
+// MetricReader returns the reader used to collect the metrics produced by the application.
+func (a *App) MetricReader() *sdkmetric.ManualReader {
+	return a.reader
+}
+
+// Metrics collects the metrics currently recorded by the application. The test fails immediately if
+// the metrics cannot be collected.
+func (a *App) Metrics() metricdata.ResourceMetrics {
+	a.t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	if err := a.reader.Collect(context.Background(), &rm); err != nil {
+		a.t.Fatalf("yunatest: collect metrics: %v", err)
+	}
+	return rm
+}
+
+// Metric collects the metrics currently recorded by the application and returns the metric with the
+// given name. The second return value reports whether the metric was found.
+func (a *App) Metric(name string) (metricdata.Metrics, bool) {
+	a.t.Helper()
+
+	rm := a.Metrics()
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m, true
+			}
+		}
+	}
+	return metricdata.Metrics{}, false
+}
+
+// RequestCount returns the number of requests recorded by the http.server.request.duration histogram
+// for the given method, route pattern, and response status code.
+func (a *App) RequestCount(method, route string, status int) uint64 {
+	a.t.Helper()
+
+	m, ok := a.Metric(RequestDurationMetric)
+	if !ok {
+		return 0
+	}
+	hist, ok := m.Data.(metricdata.Histogram[float64])
+	if !ok {
+		a.t.Fatalf("yunatest: %s is a %T, expected a float64 histogram", RequestDurationMetric, m.Data)
+	}
+
+	var count uint64
+	for _, dp := range hist.DataPoints {
+		if hasAttr(dp.Attributes, "http.request.method", attribute.StringValue(method)) &&
+			hasAttr(dp.Attributes, "http.route", attribute.StringValue(route)) &&
+			hasAttr(dp.Attributes, "http.response.status_code", attribute.IntValue(status)) {
+			count += dp.Count
+		}
+	}
+	return count
+}
+
+// principalKey is the context key of the Principal set with RequestBuilder.WithPrincipal.
+type principalKey struct{}
+
+// principalAuthenticator authenticates requests built with RequestBuilder.WithPrincipal with their
+// Principal, and all other requests with the decorated HttpAuthenticator.
+type principalAuthenticator struct {
+	next yuna.HttpAuthenticator
+}
+
+func (a *principalAuthenticator) Authenticate(r *http.Request) (yuna.Principal, error) {
+	if principal, ok := r.Context().Value(principalKey{}).(yuna.Principal); ok {
+		return principal, nil
+	}
+	return a.next.Authenticate(r)
+}
+
+func hasAttr(set attribute.Set, key attribute.Key, want attribute.Value) bool {
+	val, ok := set.Value(key)
+	return ok && val == want
+}
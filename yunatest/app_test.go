@@ -0,0 +1,160 @@
+package yunatest
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/jkratz55/yuna"
+)
+
+// testPrincipal is a Principal injected by the tests.
+type testPrincipal struct {
+	name  string
+	roles []string
+}
+
+func (p testPrincipal) Name() string      { return p.name }
+func (p testPrincipal) SubjectID() string { return p.name }
+func (p testPrincipal) Anonymous() bool   { return false }
+
+func (p testPrincipal) HasRole(role string) bool {
+	for _, r := range p.roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (p testPrincipal) Attribute(string) (any, bool) { return nil, false }
+
+// failingAuthenticator fails every request, so any request it authenticates receives a 500.
+type failingAuthenticator struct {
+	calls int
+}
+
+func (a *failingAuthenticator) Authenticate(*http.Request) (yuna.Principal, error) {
+	a.calls++
+	return nil, errors.New("authentication backend unavailable")
+}
+
+func whoAmI(r *yuna.Request) yuna.Responder {
+	principal, ok := yuna.PrincipalFromCtx(r.Context())
+	if !ok {
+		return yuna.Unauthorized()
+	}
+	return yuna.Ok(map[string]string{"name": principal.Name()})
+}
+
+func TestWithPrincipal(t *testing.T) {
+	global := &failingAuthenticator{}
+	route := &failingAuthenticator{}
+	app := New(t, yuna.WithAuthentication(global))
+	app.Yuna().With(yuna.Authenticated()).Get("/me", whoAmI)
+	app.Yuna().With(yuna.Authenticate(route), yuna.RequireRole("admin")).Get("/admin", whoAmI)
+
+	alice := testPrincipal{name: "alice", roles: []string{"admin"}}
+	app.Get("/me").WithPrincipal(alice).Do().
+		AssertStatus(http.StatusOK).
+		AssertJSON(map[string]string{"name": "alice"})
+	app.Get("/admin").WithPrincipal(alice).Do().
+		AssertStatus(http.StatusOK).
+		AssertJSON(map[string]string{"name": "alice"})
+	app.Get("/admin").WithPrincipal(testPrincipal{name: "bob"}).Do().
+		AssertProblem(http.StatusForbidden)
+
+	if global.calls != 0 || route.calls != 0 {
+		t.Errorf("authenticators called %d and %d times for requests with a principal", global.calls, route.calls)
+	}
+
+	// Without a principal the authenticators are invoked as usual.
+	app.Get("/me").Do().AssertProblem(http.StatusInternalServerError)
+	if global.calls != 1 {
+		t.Errorf("global authenticator called %d times, want 1", global.calls)
+	}
+}
+
+func TestWithPrincipalWithoutAuthentication(t *testing.T) {
+	app := New(t)
+	app.Yuna().Get("/me", whoAmI)
+
+	app.Get("/me").WithPrincipal(testPrincipal{name: "alice"}).Do().
+		AssertStatus(http.StatusOK).
+		AssertJSON(map[string]string{"name": "alice"})
+	app.Get("/me").Do().AssertProblem(http.StatusUnauthorized)
+}
+
+func TestRequestCount(t *testing.T) {
+	app := New(t)
+	app.Yuna().Get("/users/{id}", func(r *yuna.Request) yuna.Responder {
+		if r.PathParam("id").String() == "0" {
+			return yuna.NotFound()
+		}
+		return yuna.Ok(map[string]string{"id": r.PathParam("id").String()})
+	})
+
+	if got := app.RequestCount(http.MethodGet, "/users/{id}", http.StatusOK); got != 0 {
+		t.Errorf("RequestCount() before any request = %d, want 0", got)
+	}
+
+	app.Get("/users/{id}").PathParam("id", "1").Do().AssertStatus(http.StatusOK)
+	app.Get("/users/{id}").PathParam("id", "2").Do().AssertStatus(http.StatusOK)
+	app.Get("/users/{id}").PathParam("id", "0").Do().AssertStatus(http.StatusNotFound)
+
+	if got := app.RequestCount(http.MethodGet, "/users/{id}", http.StatusOK); got != 2 {
+		t.Errorf("RequestCount(200) = %d, want 2", got)
+	}
+	if got := app.RequestCount(http.MethodGet, "/users/{id}", http.StatusNotFound); got != 1 {
+		t.Errorf("RequestCount(404) = %d, want 1", got)
+	}
+	if got := app.RequestCount(http.MethodPost, "/users/{id}", http.StatusOK); got != 0 {
+		t.Errorf("RequestCount(POST) = %d, want 0", got)
+	}
+}
+
+func TestSpans(t *testing.T) {
+	app := New(t)
+	app.Yuna().Get("/users/{id}", func(*yuna.Request) yuna.Responder {
+		return yuna.NoContent()
+	})
+
+	app.Get("/users/{id}").PathParam("id", "42").Do().AssertStatus(http.StatusNoContent)
+
+	var found bool
+	for _, span := range app.Spans() {
+		if span.Name() != "GET /users/{id}" {
+			continue
+		}
+		found = true
+		for _, attr := range span.Attributes() {
+			if attr.Key == "http.response.status_code" && attr.Value != attribute.IntValue(http.StatusNoContent) {
+				t.Errorf("span %s = %v, want %d", attr.Key, attr.Value.Emit(), http.StatusNoContent)
+			}
+		}
+	}
+	if !found {
+		names := make([]string, 0, len(app.Spans()))
+		for _, span := range app.Spans() {
+			names = append(names, span.Name())
+		}
+		t.Errorf("no span for the request, got %q", names)
+	}
+}
+
+func TestOpsRequest(t *testing.T) {
+	app := New(t, yuna.WithHealthChecks())
+	app.OpsRequest(http.MethodGet, "/healthz/live").Do().AssertStatus(http.StatusOK)
+}
+
+func TestNewCopiesOptions(t *testing.T) {
+	opts := make([]yuna.ServerOption, 1, 8)
+	opts[0] = yuna.WithHTTPPort(9000)
+	New(t, opts...)
+
+	if extended := opts[:cap(opts)]; extended[1] != nil {
+		t.Error("New() wrote to the backing array of the options")
+	}
+}
@@ -0,0 +1,166 @@
+package yunatest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/jkratz55/yuna"
+)
+
+// RequestBuilder builds a request and dispatches it to the handler of an App.
+type RequestBuilder struct {
+	t          testing.TB
+	handler    http.Handler
+	method     string
+	path       string
+	pathParams map[string]string
+	query      url.Values
+	header     http.Header
+	cookies    []*http.Cookie
+	body       io.Reader
+	ctx        context.Context
+	principal  yuna.Principal
+}
+
+func newRequestBuilder(t testing.TB, handler http.Handler, method, path string) *RequestBuilder {
+	return &RequestBuilder{
+		t:          t,
+		handler:    handler,
+		method:     method,
+		path:       path,
+		pathParams: make(map[string]string),
+		query:      make(url.Values),
+		header:     make(http.Header),
+		ctx:        context.Background(),
+	}
+}
+
+// PathParam sets the value of a URL parameter in the path, such as {id} in /users/{id}. The value
+// is escaped as a path segment.
+func (rb *RequestBuilder) PathParam(name, value string) *RequestBuilder {
+	rb.pathParams[name] = value
+	return rb
+}
+
+// Query adds a query parameter to the request.
+func (rb *RequestBuilder) Query(key string, values ...string) *RequestBuilder {
+	for _, val := range values {
+		rb.query.Add(key, val)
+	}
+	return rb
+}
+
+// Header adds a header to the request.
+func (rb *RequestBuilder) Header(key string, values ...string) *RequestBuilder {
+	for _, val := range values {
+		rb.header.Add(key, val)
+	}
+	return rb
+}
+
+// Cookie adds a cookie to the request.
+func (rb *RequestBuilder) Cookie(cookie *http.Cookie) *RequestBuilder {
+	rb.cookies = append(rb.cookies, cookie)
+	return rb
+}
+
+// Body sets the body of the request and its Content-Type.
+func (rb *RequestBuilder) Body(contentType string, body io.Reader) *RequestBuilder {
+	rb.header.Set(yuna.HeaderContentType, contentType)
+	rb.body = body
+	return rb
+}
+
+// JSON encodes v as JSON and uses it as the body of the request. The test fails immediately if v
+// cannot be encoded.
+func (rb *RequestBuilder) JSON(v any) *RequestBuilder {
+	rb.t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		rb.t.Fatalf("yunatest: encode request body: %v", err)
+	}
+	return rb.Body(yuna.MIMEApplicationJSON, bytes.NewReader(data))
+}
+
+// Context sets the context of the request.
+func (rb *RequestBuilder) Context(ctx context.Context) *RequestBuilder {
+	rb.ctx = ctx
+	return rb
+}
+
+// WithPrincipal injects the Principal into the context of the request, as if the request had been
+// authenticated. The App authenticates the request with the Principal in place of the
+// HttpAuthenticators used by the Authenticate middleware, including the one configured with
+// WithAuthentication, which are not invoked for the request. See New.
+func (rb *RequestBuilder) WithPrincipal(p yuna.Principal) *RequestBuilder {
+	rb.principal = p
+	return rb
+}
+
+// Build returns the http.Request without dispatching it.
+func (rb *RequestBuilder) Build() *http.Request {
+	rb.t.Helper()
+
+	target := rb.expandPath()
+	if len(rb.query) > 0 {
+		target += "?" + rb.query.Encode()
+	}
+
+	ctx := rb.ctx
+	if rb.principal != nil {
+		// The Principal is returned by the authenticator decorator installed by New, and is also
+		// available to applications without authentication.
+		ctx = yuna.WithPrincipal(ctx, rb.principal)
+		ctx = context.WithValue(ctx, principalKey{}, rb.principal)
+	}
+
+	req := httptest.NewRequestWithContext(ctx, rb.method, target, rb.body)
+	for key, values := range rb.header {
+		req.Header[key] = values
+	}
+	for _, cookie := range rb.cookies {
+		req.AddCookie(cookie)
+	}
+	return req
+}
+
+// Do dispatches the request to the handler and returns the recorded response.
+func (rb *RequestBuilder) Do() *Response {
+	rb.t.Helper()
+
+	req := rb.Build()
+	rec := httptest.NewRecorder()
+	rb.handler.ServeHTTP(rec, req)
+
+	return &Response{
+		t:   rb.t,
+		rec: rec,
+		req: req,
+	}
+}
+
+// pathParamPattern matches URL parameters in a route pattern, including those with a regular
+// expression such as {id:[0-9]+}.
+var pathParamPattern = regexp.MustCompile(`\{([^{}:]+)(:[^{}]*(\{[^{}]*\}[^{}]*)*)?\}`)
+
+func (rb *RequestBuilder) expandPath() string {
+	rb.t.Helper()
+
+	path := pathParamPattern.ReplaceAllStringFunc(rb.path, func(param string) string {
+		name := pathParamPattern.FindStringSubmatch(param)[1]
+		val, ok := rb.pathParams[name]
+		if !ok {
+			rb.t.Fatalf("yunatest: no value for path parameter %q in %s", name, rb.path)
+		}
+		return url.PathEscape(val)
+	})
+	return path
+}
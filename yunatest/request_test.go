@@ -0,0 +1,157 @@
+package yunatest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/jkratz55/yuna"
+)
+
+// recordingTB records the failures reported by the assertions under test instead of failing the
+// test. Fatalf panics with errFatal, which is recovered by run.
+type recordingTB struct {
+	testing.TB
+	failures []string
+}
+
+var errFatal = fmt.Errorf("fatal")
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) Fatalf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+	panic(errFatal)
+}
+
+// run calls fn, recovering from a call to Fatalf.
+func (r *recordingTB) run(fn func()) {
+	defer func() {
+		if v := recover(); v != nil && v != errFatal {
+			panic(v)
+		}
+	}()
+	fn()
+}
+
+func TestRequestBuilderPath(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		params map[string]string
+		want   string
+	}{
+		{
+			name:   "param",
+			path:   "/users/{id}",
+			params: map[string]string{"id": "42"},
+			want:   "/users/42",
+		},
+		{
+			name:   "several params",
+			path:   "/users/{userId}/orders/{orderId}",
+			params: map[string]string{"userId": "7", "orderId": "abc"},
+			want:   "/users/7/orders/abc",
+		},
+		{
+			name:   "param with regular expression",
+			path:   "/orders/{id:[0-9]+}",
+			params: map[string]string{"id": "123"},
+			want:   "/orders/123",
+		},
+		{
+			name:   "regular expression with braces",
+			path:   "/codes/{code:[A-Z]{3}}/items",
+			params: map[string]string{"code": "ABC"},
+			want:   "/codes/ABC/items",
+		},
+		{
+			name:   "value is escaped as a path segment",
+			path:   "/files/{name}",
+			params: map[string]string{"name": "a b/c?d"},
+			want:   "/files/a%20b%2Fc%3Fd",
+		},
+		{
+			name: "no params",
+			path: "/health",
+			want: "/health",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rb := newRequestBuilder(t, http.NotFoundHandler(), http.MethodGet, tt.path)
+			for name, val := range tt.params {
+				rb.PathParam(name, val)
+			}
+			if got := rb.Build().URL.EscapedPath(); got != tt.want {
+				t.Errorf("path = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestBuilderMissingPathParam(t *testing.T) {
+	tb := &recordingTB{TB: t}
+	tb.run(func() {
+		newRequestBuilder(tb, http.NotFoundHandler(), http.MethodGet, "/users/{id}").Build()
+		t.Error("Build() returned without a value for the path parameter")
+	})
+	if len(tb.failures) != 1 {
+		t.Errorf("failures = %q, want one", tb.failures)
+	}
+}
+
+func TestRequestBuilderRouting(t *testing.T) {
+	app := New(t)
+	app.Yuna().Get("/orders/{id:[0-9]+}/items/{name}", func(r *yuna.Request) yuna.Responder {
+		return yuna.Ok(map[string]string{
+			"id":   r.PathParam("id").String(),
+			"name": r.PathParam("name").String(),
+		})
+	})
+
+	app.Get("/orders/{id:[0-9]+}/items/{name}").
+		PathParam("id", "12").
+		PathParam("name", "blue shirt").
+		Do().
+		AssertStatus(http.StatusOK).
+		AssertJSON(map[string]string{"id": "12", "name": "blue shirt"})
+
+	app.Get("/orders/{id:[0-9]+}/items/{name}").
+		PathParam("id", "abc").
+		PathParam("name", "shirt").
+		Do().
+		AssertProblem(http.StatusNotFound)
+}
+
+func TestRequestBuilderBuild(t *testing.T) {
+	req := newRequestBuilder(t, http.NotFoundHandler(), http.MethodPost, "/orders").
+		Query("status", "open", "paid").
+		Header("X-Tenant", "acme").
+		Cookie(&http.Cookie{Name: "session", Value: "s1"}).
+		JSON(map[string]int{"quantity": 2}).
+		Build()
+
+	if got := req.URL.RawQuery; got != "status=open&status=paid" {
+		t.Errorf("query = %q", got)
+	}
+	if got := req.Header.Get("X-Tenant"); got != "acme" {
+		t.Errorf("X-Tenant = %q", got)
+	}
+	if cookie, err := req.Cookie("session"); err != nil || cookie.Value != "s1" {
+		t.Errorf("session cookie = %v, %v", cookie, err)
+	}
+	if got := req.Header.Get(yuna.HeaderContentType); got != yuna.MIMEApplicationJSON {
+		t.Errorf("Content-Type = %q", got)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if string(body) != `{"quantity":2}` {
+		t.Errorf("body = %s", body)
+	}
+}
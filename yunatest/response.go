@@ -0,0 +1,217 @@
+package yunatest
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/jkratz55/yuna"
+)
+
+// Response is the response recorded for a request dispatched by a RequestBuilder. The assertion
+// methods report failures using testing.TB.Errorf and return the Response so assertions can be
+// chained.
+type Response struct {
+	t   testing.TB
+	rec *httptest.ResponseRecorder
+	req *http.Request
+}
+
+// Request returns the request that produced the response.
+func (r *Response) Request() *http.Request {
+	return r.req
+}
+
+// Recorder returns the underlying httptest.ResponseRecorder.
+func (r *Response) Recorder() *httptest.ResponseRecorder {
+	return r.rec
+}
+
+// Status returns the HTTP status code of the response.
+func (r *Response) Status() int {
+	return r.rec.Code
+}
+
+// Header returns the headers of the response.
+func (r *Response) Header() http.Header {
+	return r.rec.Result().Header
+}
+
+// Body returns the body of the response.
+func (r *Response) Body() []byte {
+	return r.rec.Body.Bytes()
+}
+
+// DecodeJSON decodes the body of the response into v. The test fails immediately if the body
+// cannot be decoded.
+func (r *Response) DecodeJSON(v any) *Response {
+	r.t.Helper()
+
+	if err := json.Unmarshal(r.Body(), v); err != nil {
+		r.t.Fatalf("yunatest: decode response body: %v\nbody: %s", err, r.Body())
+	}
+	return r
+}
+
+// Problem decodes an application/problem+json response into ProblemDetails. The test fails
+// immediately if the response is not a problem or cannot be decoded.
+func (r *Response) Problem() *yuna.ProblemDetails {
+	r.t.Helper()
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header().Get(yuna.HeaderContentType))
+	if mediaType != yuna.MIMEApplicationProblemJSON {
+		r.t.Fatalf("yunatest: expected Content-Type %s, got %q\nbody: %s",
+			yuna.MIMEApplicationProblemJSON, r.Header().Get(yuna.HeaderContentType), r.Body())
+	}
+
+	var problem yuna.ProblemDetails
+	r.DecodeJSON(&problem)
+	return &problem
+}
+
+// AssertStatus asserts the response has the given HTTP status code.
+func (r *Response) AssertStatus(status int) *Response {
+	r.t.Helper()
+
+	if r.Status() != status {
+		r.t.Errorf("yunatest: expected status %d, got %d\nbody: %s", status, r.Status(), r.Body())
+	}
+	return r
+}
+
+// AssertHeader asserts the response has a header with the given value. If multiple values are
+// present the assertion passes if any of them match.
+func (r *Response) AssertHeader(key, value string) *Response {
+	r.t.Helper()
+
+	values := r.Header().Values(key)
+	if !slices.Contains(values, value) {
+		r.t.Errorf("yunatest: expected header %s to be %q, got %q", key, value, values)
+	}
+	return r
+}
+
+// AssertHeaderAbsent asserts the response doesn't have the given header.
+func (r *Response) AssertHeaderAbsent(key string) *Response {
+	r.t.Helper()
+
+	if values := r.Header().Values(key); len(values) > 0 {
+		r.t.Errorf("yunatest: expected header %s to be absent, got %q", key, values)
+	}
+	return r
+}
+
+// AssertContentType asserts the media type of the response, ignoring any parameters such as charset.
+func (r *Response) AssertContentType(mediaType string) *Response {
+	r.t.Helper()
+
+	actual, _, _ := mime.ParseMediaType(r.Header().Get(yuna.HeaderContentType))
+	if actual != mediaType {
+		r.t.Errorf("yunatest: expected Content-Type %s, got %q", mediaType, r.Header().Get(yuna.HeaderContentType))
+	}
+	return r
+}
+
+// AssertBody asserts the body of the response is exactly the given string.
+func (r *Response) AssertBody(body string) *Response {
+	r.t.Helper()
+
+	if string(r.Body()) != body {
+		r.t.Errorf("yunatest: expected body %q, got %q", body, r.Body())
+	}
+	return r
+}
+
+// AssertJSON asserts the body of the response is JSON equivalent to expected. Both values are
+// compared in their decoded form, so formatting and the order of object members doesn't matter.
+// Expected may be any value that can be encoded as JSON. A string, []byte, or json.RawMessage is
+// treated as JSON text.
+func (r *Response) AssertJSON(expected any) *Response {
+	r.t.Helper()
+
+	want, err := normalizeJSON(expected)
+	if err != nil {
+		r.t.Fatalf("yunatest: encode expected JSON: %v", err)
+	}
+
+	var got any
+	if err := json.Unmarshal(r.Body(), &got); err != nil {
+		r.t.Errorf("yunatest: response body is not valid JSON: %v\nbody: %s", err, r.Body())
+		return r
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		wantJSON, _ := json.Marshal(want)
+		r.t.Errorf("yunatest: expected JSON body %s, got %s", wantJSON, r.Body())
+	}
+	return r
+}
+
+// AssertProblem asserts the response is an application/problem+json response with the given status
+// code.
+func (r *Response) AssertProblem(status int) *Response {
+	r.t.Helper()
+
+	r.AssertStatus(status)
+	problem := r.Problem()
+	if problem.StatusCode != status {
+		r.t.Errorf("yunatest: expected problem status %d, got %d", status, problem.StatusCode)
+	}
+	return r
+}
+
+// AssertViolation asserts the response is a problem with violations for the given field. If
+// messages are provided, each of them must be present for the field.
+func (r *Response) AssertViolation(field string, messages ...string) *Response {
+	r.t.Helper()
+
+	violations := r.Problem().Violations()
+	actual, ok := violations[field]
+	if !ok {
+		r.t.Errorf("yunatest: expected violation for field %q, got %v", field, violations)
+		return r
+	}
+	for _, msg := range messages {
+		if !slices.Contains(actual, msg) {
+			r.t.Errorf("yunatest: expected violation %q for field %q, got %q", msg, field, actual)
+		}
+	}
+	return r
+}
+
+// AssertViolations asserts the response is a problem with exactly the given violations.
+func (r *Response) AssertViolations(expected yuna.Violations) *Response {
+	r.t.Helper()
+
+	actual := r.Problem().Violations()
+	if (len(expected) > 0 || len(actual) > 0) && !reflect.DeepEqual(expected, actual) {
+		r.t.Errorf("yunatest: expected violations %v, got %v", expected, actual)
+	}
+	return r
+}
+
+func normalizeJSON(v any) (any, error) {
+	var data []byte
+	switch val := v.(type) {
+	case json.RawMessage:
+		data = val
+	case []byte:
+		data = val
+	case string:
+		data = []byte(val)
+	default:
+		var err error
+		data, err = json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var out any
+	err := json.Unmarshal(data, &out)
+	return out, err
+}
@@ -0,0 +1,109 @@
+package yunatest
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jkratz55/yuna"
+)
+
+type createUserRequest struct {
+	Name  string `json:"name" validate:"required"`
+	Email string `json:"email" validate:"required,email"`
+	Age   int    `json:"age" validate:"min=18"`
+}
+
+func createUser(r *yuna.Request) yuna.Responder {
+	var req createUserRequest
+	if problem := r.DecodeAndValidate(&req); problem != nil {
+		return problem
+	}
+	return yuna.Created("/users/1").Body(req)
+}
+
+func TestAssertProblem(t *testing.T) {
+	app := New(t)
+	app.Yuna().Post("/users", createUser)
+
+	resp := app.Post("/users").JSON(map[string]any{"email": "not an email", "age": 12}).Do()
+	resp.AssertProblem(http.StatusUnprocessableEntity).
+		AssertContentType(yuna.MIMEApplicationProblemJSON).
+		AssertViolation("name", "is required").
+		AssertViolation("email").
+		AssertViolation("age")
+
+	problem := resp.Problem()
+	if problem.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("problem status = %d", problem.StatusCode)
+	}
+	if got := len(problem.Violations()); got != 3 {
+		t.Errorf("violations = %v, want 3 fields", problem.Violations())
+	}
+	resp.AssertViolations(problem.Violations())
+
+	app.Post("/users").JSON(map[string]any{"name": "Ann", "email": "ann@example.com", "age": 30}).Do().
+		AssertStatus(http.StatusCreated).
+		AssertJSON(map[string]any{"name": "Ann", "email": "ann@example.com", "age": 30})
+}
+
+func TestAssertionsReportFailures(t *testing.T) {
+	app := New(t)
+	app.Yuna().Post("/users", createUser)
+
+	tests := []struct {
+		name   string
+		assert func(r *Response)
+	}{
+		{
+			name:   "status",
+			assert: func(r *Response) { r.AssertStatus(http.StatusOK) },
+		},
+		{
+			name:   "problem status",
+			assert: func(r *Response) { r.AssertProblem(http.StatusBadRequest) },
+		},
+		{
+			name:   "violation for another field",
+			assert: func(r *Response) { r.AssertViolation("password") },
+		},
+		{
+			name:   "violation message",
+			assert: func(r *Response) { r.AssertViolation("name", "must be unique") },
+		},
+		{
+			name:   "violations",
+			assert: func(r *Response) { r.AssertViolations(yuna.Violations{"name": {"is required"}}) },
+		},
+		{
+			name:   "header",
+			assert: func(r *Response) { r.AssertHeader(yuna.HeaderContentType, yuna.MIMEApplicationJSON) },
+		},
+		{
+			name:   "json",
+			assert: func(r *Response) { r.AssertJSON(map[string]any{"status": 422}) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := &recordingTB{TB: t}
+			resp := newRequestBuilder(tb, app.Yuna(), http.MethodPost, "/users").JSON(map[string]any{}).Do()
+			tb.run(func() { tt.assert(resp) })
+			if len(tb.failures) == 0 {
+				t.Error("assertion didn't report a failure")
+			}
+		})
+	}
+}
+
+func TestProblemOfNonProblemResponse(t *testing.T) {
+	app := New(t)
+	app.Yuna().Get("/ok", func(*yuna.Request) yuna.Responder { return yuna.Ok("ok") })
+
+	tb := &recordingTB{TB: t}
+	resp := newRequestBuilder(tb, app.Yuna(), http.MethodGet, "/ok").Do()
+	tb.run(func() { resp.Problem() })
+	if len(tb.failures) == 0 {
+		t.Error("Problem() didn't fail for an application/json response")
+	}
+}